@@ -1,61 +1,640 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mattn/go-isatty"
+	"github.com/whexy/wenxuan-dev-init/pkg/events"
 	"github.com/whexy/wenxuan-dev-init/pkg/executor"
 	"github.com/whexy/wenxuan-dev-init/pkg/installer"
 	"github.com/whexy/wenxuan-dev-init/pkg/logger"
+	"github.com/whexy/wenxuan-dev-init/pkg/profile"
+	"github.com/whexy/wenxuan-dev-init/pkg/state"
 	"github.com/whexy/wenxuan-dev-init/pkg/tui"
+	"github.com/whexy/wenxuan-dev-init/pkg/ui"
 )
 
+// Exit codes
+const (
+	exitOK            = 0
+	exitFailure       = 1
+	exitUsage         = 2
+	exitAborted       = 3
+	exitRerunRequired = 4
+	exitPartial       = 5
+	exitTotalFailure  = 6
+)
+
+// interruptGrace is how long an interrupted TUI run gets to stop before the program exits
+const interruptGrace = 10 * time.Second
+
 var (
-	githubTokenRef       = flag.String("github-token", "op://Developer/GitHub Personal Access Token/token", "1Password reference for GitHub token")
-	tailscaleAuthKeyRef  = flag.String("tailscale-authkey", "op://Developer/tailscale auth key/credential", "1Password reference for Tailscale auth key")
-	useServiceAccount    = flag.Bool("use-service-account", false, "Use 1Password service account token (requires OP_SERVICE_ACCOUNT_TOKEN)")
+	profilePath         = flag.String("profile", "", "Path to a profile file (default: "+profile.DefaultPath()+" if present)")
+	githubTokenRef      = flag.String("github-token", profile.DefaultGitHubTokenRef, "1Password reference for GitHub token")
+	tailscaleAuthKeyRef = flag.String("tailscale-authkey", profile.DefaultTailscaleAuthKeyRef, "1Password reference for Tailscale auth key")
+	useServiceAccount   = flag.Bool("use-service-account", false, "Use 1Password service account token (requires OP_SERVICE_ACCOUNT_TOKEN)")
+	selectOptions       = flag.String("select", "", "Comma-separated options to enable (e.g. install_git,setup_github)")
+	skipOptions         = flag.String("skip", "", "Comma-separated options to disable")
+	onlyOptions         = flag.String("only", "", "Comma-separated options to run; every other option is disabled")
+	onDevboxFailure     = flag.String("on-devbox-failure", "", "What to do when devbox fails: ask, system or abort (default: ask, or abort when non-interactive)")
+	packageManager      = flag.String("package-manager", "", "System package manager to use instead of detecting one (e.g. brew)")
+	eventsJSON          = flag.String("events-json", "", "Write run events as JSON lines to this file (- for stdout)")
+	resumeRun           = flag.Bool("resume", false, "Resume an unfinished run without asking")
+	freshRun            = flag.Bool("fresh", false, "Discard any unfinished run and start over")
+	nonInteractive      bool
 )
 
+func init() {
+	flag.BoolVar(&nonInteractive, "non-interactive", false, "Skip the TUI and never prompt; selections come from the profile and flags")
+	flag.BoolVar(&nonInteractive, "yes", false, "Alias for --non-interactive")
+}
+
+// usageError marks errors caused by invalid command-line input
+type usageError struct{ err error }
+
+func (e usageError) Error() string { return e.err.Error() }
+func (e usageError) Unwrap() error { return e.err }
+
 func main() {
 	flag.Parse()
 
-	// Set the global references
-	installer.SetGitHubTokenReference(*githubTokenRef)
-	installer.SetTailscaleAuthKeyReference(*tailscaleAuthKeyRef)
-	installer.SetUseServiceAccount(*useServiceAccount)
-
 	if err := run(); err != nil {
 		logger.Error(err.Error())
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
 
+func exitCode(err error) int {
+	var usageErr usageError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &usageErr):
+		return exitUsage
+	case errors.Is(err, executor.ErrAborted):
+		return exitAborted
+	case errors.Is(err, executor.ErrRerunRequired):
+		return exitRerunRequired
+	case errors.Is(err, executor.ErrPartialFailure):
+		return exitPartial
+	case errors.Is(err, executor.ErrTotalFailure):
+		return exitTotalFailure
+	}
+	return exitFailure
+}
+
+// loadProfile loads the profile and applies explicitly set flags on top of it
+func loadProfile() (*profile.Profile, error) {
+	prof, err := profile.Load(*profilePath)
+	if err != nil {
+		return nil, usageError{err}
+	}
+
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "github-token":
+			prof.Secrets.GitHubToken = *githubTokenRef
+		case "tailscale-authkey":
+			prof.Secrets.TailscaleAuthKey = *tailscaleAuthKeyRef
+		case "use-service-account":
+			prof.Secrets.ServiceAccount = *useServiceAccount
+		case "package-manager":
+			prof.PackageManager = *packageManager
+		}
+	})
+
+	if err := prof.Validate(); err != nil {
+		return nil, usageError{fmt.Errorf("invalid flags: %w", err)}
+	}
+	return prof, nil
+}
+
 func run() error {
-	// Run the interactive TUI
-	options, err := runTUI()
+	if err := checkFlags(); err != nil {
+		return err
+	}
+
+	args := flag.Args()
+	if len(args) == 0 {
+		return runSetup()
+	}
+
+	switch args[0] {
+	case "plan":
+		return runPlan(args[1:])
+	case "upgrade":
+		return runUpgrade(args[1:])
+	case "uninstall":
+		return runUninstall(args[1:])
+	case "doctor":
+		return runDoctor(args[1:])
+	}
+	return usageError{fmt.Errorf("unknown command %q", args[0])}
+}
+
+// configure loads the profile and configures the installer from it
+func configure() (*profile.Profile, error) {
+	prof, err := loadProfile()
+	if err != nil {
+		return nil, err
+	}
+
+	// Set the global references
+	installer.SetGitHubTokenReference(prof.Secrets.GitHubToken)
+	installer.SetTailscaleAuthKeyReference(prof.Secrets.TailscaleAuthKey)
+	installer.SetUseServiceAccount(prof.Secrets.ServiceAccount)
+	installer.SetNonInteractive(nonInteractive)
+	if err := installer.SetPreferredPackageManager(prof.PackageManager); err != nil {
+		return nil, usageError{err}
+	}
+	return prof, nil
+}
+
+// newExecutor builds an executor for the confirmed options with the policies from the flags
+func newExecutor(options map[string]bool, prof *profile.Profile) (*executor.Executor, error) {
+	exec := executor.New(options, prof)
+	exec.SetNonInteractive(nonInteractive)
+
+	policy := *onDevboxFailure
+	if policy == "" && nonInteractive {
+		policy = executor.DevboxFailureAbort
+	}
+	if policy != "" {
+		if err := exec.SetDevboxFailurePolicy(policy); err != nil {
+			return nil, usageError{err}
+		}
+	}
+	return exec, nil
+}
+
+// prepare configures the run and builds an executor from the chosen options.
+// A nil executor means the user cancelled.
+func prepare() (*executor.Executor, error) {
+	prof, err := configure()
+	if err != nil {
+		return nil, err
+	}
+
+	options, err := chooseOptions(prof)
+	if err != nil || options == nil {
+		return nil, err
+	}
+	return newExecutor(options, prof)
+}
+
+// streamEvents subscribes the JSON-lines sink requested with --events-json and returns a function that closes it
+func streamEvents() (func(), error) {
+	switch *eventsJSON {
+	case "":
+		return func() {}, nil
+	case "-":
+		if !nonInteractive {
+			return nil, usageError{fmt.Errorf("--events-json - requires --non-interactive; the TUI needs stdout")}
+		}
+		// Keep stdout clean for the event stream
+		logger.SetOutput(os.Stderr)
+		return events.Subscribe(events.NewJSONSink(os.Stdout)), nil
+	}
+
+	f, err := os.Create(*eventsJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create event stream: %w", err)
+	}
+	unsubscribe := events.Subscribe(events.NewJSONSink(f))
+	return func() {
+		unsubscribe()
+		f.Close()
+	}, nil
+}
+
+func runSetup() error {
+	closeEvents, err := streamEvents()
+	if err != nil {
+		return err
+	}
+	defer closeEvents()
+
+	if !nonInteractive {
+		return runInteractiveSetup()
+	}
+
+	prof, err := configure()
+	if err != nil {
+		return err
+	}
+	saved, err := savedRun()
+	if err != nil {
+		return err
+	}
+
+	var options map[string]bool
+	if saved != nil {
+		options = saved.Options
+	} else if options, err = chooseOptions(prof); err != nil {
+		return err
+	}
+
+	exec, err := newExecutor(options, prof)
+	if err != nil {
+		return err
+	}
+
+	// Execute the workflow
+	if err := execute(exec, saved, options); err != nil {
+		return fmt.Errorf("execution error: %w", err)
+	}
+
+	return nil
+}
+
+// savedRun returns the state of an unfinished run to resume, or nil to start over
+func savedRun() (*state.State, error) {
+	if *resumeRun && *freshRun {
+		return nil, usageError{fmt.Errorf("--resume and --fresh are mutually exclusive")}
+	}
+	if *freshRun {
+		return nil, state.Clear()
+	}
+
+	saved, err := state.Load()
+	if err != nil {
+		// A broken state file must not block setup; it is replaced when the run starts
+		logger.Warning(err.Error())
+		return nil, nil
+	}
+	if saved == nil {
+		if *resumeRun {
+			logger.Info("No unfinished run to resume, starting over")
+		}
+		return nil, nil
+	}
+
+	stoppedAt := ""
+	if saved.Current != "" {
+		stoppedAt = fmt.Sprintf(" (stopped during %s)", saved.Current)
+	}
+	found := fmt.Sprintf("Found an unfinished run from %s%s", saved.Started.Format("Jan 2 15:04"), stoppedAt)
+
+	switch {
+	case *resumeRun:
+	case nonInteractive:
+		logger.Info(found + "; pass --resume to continue it. Starting over.")
+		return nil, nil
+	case !ui.AskYesNo(found + ". Resume it?"):
+		return nil, nil
+	}
+
+	logger.Info("Resuming; steps that already completed are skipped")
+	return saved, nil
+}
+
+// execute runs exec, persisting its progress so an interrupted run can be resumed, and forgets the run once it succeeded
+func execute(exec *executor.Executor, saved *state.State, options map[string]bool) error {
+	st := saved
+	if st == nil {
+		st = state.New(options)
+	}
+	exec.SetState(st)
+
+	manifest, err := state.LoadManifest()
+	if err != nil {
+		// Setup still works; uninstall just cannot undo what this run changes
+		logger.Warning(err.Error())
+	}
+	installer.SetManifest(manifest)
+
+	if err := exec.Execute(); err != nil {
+		return err
+	}
+	if err := state.Clear(); err != nil {
+		logger.Warning(err.Error())
+	}
+	return nil
+}
+
+// runInteractiveSetup lets the user choose options in the TUI and keeps it up to show live progress
+func runInteractiveSetup() error {
+	prof, err := configure()
+	if err != nil {
+		return err
+	}
+	if err := requireTerminal(); err != nil {
+		return err
+	}
+	saved, err := savedRun()
+	if err != nil {
+		return err
+	}
+
+	// finished is closed when a started run returns
+	finished := make(chan struct{})
+	model := tui.NewModel(prof).WithRun(func(ctx context.Context, options map[string]bool, sink events.Sink) error {
+		defer close(finished)
+		exec, err := newExecutor(options, prof)
+		if err != nil {
+			return err
+		}
+
+		// The TUI shows the progress while it owns the screen
+		defer events.Subscribe(sink)()
+		defer logger.SetOutput(logger.Output())
+		logger.SetOutput(io.Discard)
+		installer.SetContext(ctx)
+		defer installer.SetContext(nil)
+		return execute(exec, saved, options)
+	})
+	if saved != nil {
+		model = model.Resume(saved.Options)
+	}
+	p := tea.NewProgram(model, tea.WithAltScreen())
+
+	// Prompts and interactive commands such as op signin get the real terminal while they run
+	ui.SetTerminalHook(func(fn func() error) error {
+		if err := p.ReleaseTerminal(); err != nil {
+			return err
+		}
+		defer p.RestoreTerminal()
+		return fn()
+	})
+	defer ui.SetTerminalHook(nil)
+
+	finalModel, err := p.Run()
 	if err != nil {
 		return fmt.Errorf("TUI error: %w", err)
 	}
 
+	m, ok := finalModel.(tui.Model)
+	if !ok {
+		return fmt.Errorf("unexpected model type")
+	}
+
 	// User cancelled
-	if options == nil {
+	if !m.IsConfirmed() {
 		fmt.Println("\nSetup cancelled.")
 		return nil
 	}
 
-	// Execute the workflow
-	exec := executor.New(options)
-	if err := exec.Execute(); err != nil {
+	if m.Interrupted() {
+		// Give the cancelled commands a moment to be stopped and the run state to be saved
+		select {
+		case <-finished:
+		case <-time.After(interruptGrace):
+			logger.Warning("The interrupted run did not stop in time")
+		}
+	}
+
+	// The alt screen is gone, so leave the results in the scrollback
+	if results := m.Results(); len(results) > 0 {
+		executor.PrintSummary(results)
+	}
+	if m.Interrupted() {
+		return executor.ErrAborted
+	}
+	if err := m.RunError(); err != nil {
 		return fmt.Errorf("execution error: %w", err)
 	}
 
 	return nil
 }
 
-func runTUI() (map[string]bool, error) {
-	model := tui.NewModel()
+// runPlan prints what a setup run would do without touching the system
+func runPlan(args []string) error {
+	fs := flag.NewFlagSet("plan", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "Print the plan as JSON")
+	if err := fs.Parse(args); err != nil {
+		return usageError{err}
+	}
+	if *asJSON {
+		// Keep stdout clean for the JSON document
+		logger.SetOutput(os.Stderr)
+	}
+
+	exec, err := prepare()
+	if err != nil {
+		return err
+	}
+	if exec == nil {
+		fmt.Println("\nPlan cancelled.")
+		return nil
+	}
+
+	plan, err := exec.Plan()
+	if err != nil {
+		return fmt.Errorf("planning error: %w", err)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(plan)
+	}
+	plan.WriteText(os.Stdout)
+	return nil
+}
+
+// runUpgrade upgrades the managed tools and the profile's extra packages, or only the tools named in args
+func runUpgrade(args []string) error {
+	fs := flag.NewFlagSet("upgrade", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return usageError{err}
+	}
+
+	prof, err := configure()
+	if err != nil {
+		return err
+	}
+
+	tools := fs.Args()
+	if len(tools) == 0 {
+		tools = slices.Concat(executor.ManagedTools, prof.Packages)
+	}
+
+	results, err := executor.Upgrade(tools)
+	executor.PrintUpgradeSummary(results)
+	if err != nil {
+		return fmt.Errorf("upgrade error: %w", err)
+	}
+	return nil
+}
+
+// runUninstall undoes the changes setup recorded in its manifest
+func runUninstall(args []string) error {
+	fs := flag.NewFlagSet("uninstall", flag.ContinueOnError)
+	removePackages := fs.Bool("packages", false, "Also remove the packages setup installed")
+	if err := fs.Parse(args); err != nil {
+		return usageError{err}
+	}
+	if _, err := configure(); err != nil {
+		return err
+	}
+
+	manifest, err := state.LoadManifest()
+	if err != nil {
+		return err
+	}
+	if manifest.IsEmpty() {
+		logger.Info("Nothing to undo: setup has not recorded any changes on this machine")
+		return nil
+	}
+
+	logger.Step("🧹", "Uninstall will undo:")
+	for _, line := range executor.DescribeUninstall(manifest, *removePackages) {
+		logger.Println("  " + line)
+	}
+	if !nonInteractive && !ui.AskYesNo("Undo them?") {
+		return executor.ErrAborted
+	}
+
+	if err := executor.Uninstall(manifest, *removePackages); err != nil {
+		return fmt.Errorf("uninstall error: %w", err)
+	}
+	return nil
+}
+
+// runDoctor probes each integration and prints a report
+func runDoctor(args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "Print the checks as JSON")
+	if err := fs.Parse(args); err != nil {
+		return usageError{err}
+	}
+	if *asJSON {
+		// Keep stdout clean for the JSON document
+		logger.SetOutput(os.Stderr)
+	}
+
+	prof, err := configure()
+	if err != nil {
+		return err
+	}
+
+	checks, err := executor.Doctor(prof)
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if encErr := enc.Encode(checks); encErr != nil {
+			return encErr
+		}
+	} else {
+		executor.PrintDoctorReport(checks)
+	}
+	return err
+}
+
+// chooseOptions returns the confirmed selections, from the TUI or, when non-interactive, from the profile and flags.
+// A nil map means the user cancelled.
+func chooseOptions(prof *profile.Profile) (map[string]bool, error) {
+	if !nonInteractive {
+		if err := requireTerminal(); err != nil {
+			return nil, err
+		}
+
+		options, err := runTUI(prof)
+		if err != nil {
+			return nil, fmt.Errorf("TUI error: %w", err)
+		}
+		return options, nil
+	}
+
+	options := tui.NewModel(prof).GetSelectedOptions()
+	selected := *selectOptions
+	if *onlyOptions != "" {
+		for key := range options {
+			options[key] = false
+		}
+		selected = *onlyOptions
+	}
+	if err := applySelections(options, selected, *skipOptions); err != nil {
+		return nil, err
+	}
+
+	var enabled []string
+	for key, on := range options {
+		if on {
+			enabled = append(enabled, key)
+		}
+	}
+	sort.Strings(enabled)
+	if len(enabled) == 0 {
+		enabled = append(enabled, "none")
+	}
+	logger.Info(fmt.Sprintf("Selected options: %s", strings.Join(enabled, ", ")))
+
+	return options, nil
+}
+
+// checkFlags rejects global flags that cannot be used together
+func checkFlags() error {
+	if !nonInteractive && (*selectOptions != "" || *skipOptions != "" || *onlyOptions != "") {
+		return usageError{fmt.Errorf("--select, --skip and --only require --non-interactive")}
+	}
+	if *onlyOptions != "" && (*selectOptions != "" || *skipOptions != "") {
+		return usageError{fmt.Errorf("--only cannot be combined with --select or --skip")}
+	}
+	return nil
+}
+
+// requireTerminal checks that the TUI can run
+func requireTerminal() error {
+	if !isatty.IsTerminal(os.Stdin.Fd()) && !isatty.IsCygwinTerminal(os.Stdin.Fd()) {
+		return usageError{fmt.Errorf("no terminal detected; rerun with --non-interactive")}
+	}
+	return nil
+}
+
+// applySelections enables the options in the comma-separated selected list and disables those in skipped.
+// An option in both lists is a usage error.
+func applySelections(options map[string]bool, selected, skipped string) error {
+	for _, key := range splitList(selected) {
+		if slices.Contains(splitList(skipped), key) {
+			return usageError{fmt.Errorf("option %q is both selected and skipped", key)}
+		}
+	}
+	if err := applyOptionList(options, selected, true); err != nil {
+		return err
+	}
+	return applyOptionList(options, skipped, false)
+}
+
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// applyOptionList sets every key in the comma-separated list to value
+func applyOptionList(options map[string]bool, list string, value bool) error {
+	for _, key := range splitList(list) {
+		if _, ok := options[key]; !ok {
+			known := make([]string, 0, len(options))
+			for k := range options {
+				known = append(known, k)
+			}
+			sort.Strings(known)
+			return usageError{fmt.Errorf("unknown option %q (known: %s)", key, strings.Join(known, ", "))}
+		}
+		options[key] = value
+	}
+	return nil
+}
+
+func runTUI(prof *profile.Profile) (map[string]bool, error) {
+	model := tui.NewModel(prof)
 	p := tea.NewProgram(model, tea.WithAltScreen())
 
 	finalModel, err := p.Run()
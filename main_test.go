@@ -0,0 +1,188 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/whexy/wenxuan-dev-init/pkg/executor"
+	"github.com/whexy/wenxuan-dev-init/pkg/logger"
+	"github.com/whexy/wenxuan-dev-init/pkg/profile"
+)
+
+func TestApplySelections(t *testing.T) {
+	defaults := map[string]bool{"install_git": false, "install_gh": true, "setup_github": true}
+
+	tests := []struct {
+		name              string
+		selected, skipped string
+		want              map[string]bool
+		wantErr           string
+	}{
+		{name: "none", want: defaults},
+		{
+			name:     "select and skip",
+			selected: "install_git",
+			skipped:  " install_gh , setup_github",
+			want:     map[string]bool{"install_git": true, "install_gh": false, "setup_github": false},
+		},
+		{
+			name:     "empty items",
+			selected: "install_git,,",
+			skipped:  ", install_gh,",
+			want:     map[string]bool{"install_git": true, "install_gh": false, "setup_github": true},
+		},
+		{name: "unknown option", selected: "install_vim", wantErr: `unknown option "install_vim"`},
+		{name: "conflict", selected: "install_git,install_gh", skipped: "install_gh", wantErr: `option "install_gh" is both selected and skipped`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := maps.Clone(defaults)
+			err := applySelections(options, tt.selected, tt.skipped)
+			if tt.wantErr != "" {
+				var usageErr usageError
+				if !errors.As(err, &usageErr) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("applySelections() error = %v, want a usage error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !maps.Equal(options, tt.want) {
+				t.Errorf("options = %v, want %v", options, tt.want)
+			}
+		})
+	}
+}
+
+// TestDoctorFixesParse runs every setup command doctor suggests through the flag parsing and validation of a real run
+// and checks that it selects nothing but the option it names
+func TestDoctorFixesParse(t *testing.T) {
+	// A machine without any tools, and one where gh and tailscale are installed but not signed in
+	machines := map[string]string{
+		"gh":        "#!/bin/sh\necho 'You are not logged into any GitHub hosts' >&2\nexit 1\n",
+		"tailscale": "#!/bin/sh\nprintf '{\"BackendState\":\"NeedsLogin\"}\\n'\nexit 1\n",
+	}
+	empty, installed := t.TempDir(), t.TempDir()
+	for name, script := range machines {
+		if err := os.WriteFile(filepath.Join(installed, name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var fixes []string
+	for _, dir := range []string{empty, installed} {
+		t.Setenv("PATH", dir)
+		checks, _ := executor.Doctor(profile.Default())
+		for _, c := range checks {
+			if strings.HasPrefix(c.Fix, "wenxuan-dev-init ") && !slices.Contains(fixes, c.Fix) {
+				fixes = append(fixes, c.Fix)
+			}
+		}
+	}
+	if len(fixes) != 7 {
+		t.Fatalf("doctor suggested %d setup commands, want one per option it checks: %q", len(fixes), fixes)
+	}
+
+	prev := logger.Output()
+	logger.SetOutput(io.Discard)
+	t.Cleanup(func() {
+		logger.SetOutput(prev)
+		nonInteractive, *selectOptions, *skipOptions, *onlyOptions = false, "", "", ""
+	})
+
+	for _, fix := range fixes {
+		nonInteractive, *selectOptions, *skipOptions, *onlyOptions = false, "", "", ""
+		if err := flag.CommandLine.Parse(strings.Fields(fix)[1:]); err != nil {
+			t.Errorf("%s: %v", fix, err)
+			continue
+		}
+		if err := checkFlags(); err != nil {
+			t.Errorf("%s: %v", fix, err)
+			continue
+		}
+		if flag.NArg() != 0 {
+			t.Errorf("%s: runs the %q command instead of setup", fix, flag.Arg(0))
+		}
+
+		options, err := chooseOptions(profile.Default())
+		if err != nil {
+			t.Errorf("%s: %v", fix, err)
+			continue
+		}
+		// A fix must not log in, authenticate or apply dotfiles besides what it names
+		args := strings.Fields(fix)
+		want := args[len(args)-1]
+		for key, on := range options {
+			if on != (key == want) {
+				t.Errorf("%s: option %q enabled = %v, want only %q enabled", fix, key, on, want)
+			}
+		}
+	}
+}
+
+func TestCheckFlags(t *testing.T) {
+	t.Cleanup(func() {
+		nonInteractive, *selectOptions, *skipOptions, *onlyOptions = false, "", "", ""
+	})
+
+	tests := []struct {
+		name                 string
+		nonInteractive       bool
+		selected, skip, only string
+		wantErr              string
+	}{
+		{name: "interactive"},
+		{name: "select", nonInteractive: true, selected: "install_git"},
+		{name: "only", nonInteractive: true, only: "install_git"},
+		{name: "only in the TUI", only: "install_git", wantErr: "require --non-interactive"},
+		{name: "only with select", nonInteractive: true, only: "install_git", selected: "install_gh", wantErr: "--only cannot be combined"},
+		{name: "only with skip", nonInteractive: true, only: "install_git", skip: "install_gh", wantErr: "--only cannot be combined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nonInteractive, *selectOptions, *skipOptions, *onlyOptions = tt.nonInteractive, tt.selected, tt.skip, tt.only
+			err := checkFlags()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			var usageErr usageError
+			if !errors.As(err, &usageErr) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkFlags() error = %v, want a usage error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, exitOK},
+		{errors.New("boom"), exitFailure},
+		{usageError{errors.New("bad flag")}, exitUsage},
+		{fmt.Errorf("devbox: %w", executor.ErrAborted), exitAborted},
+		{executor.ErrRerunRequired, exitRerunRequired},
+		{fmt.Errorf("%w: 1 of the selected steps did not complete", executor.ErrPartialFailure), exitPartial},
+		{fmt.Errorf("%w: 2 of the selected steps did not complete", executor.ErrTotalFailure), exitTotalFailure},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
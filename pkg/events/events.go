@@ -0,0 +1,137 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Kind identifies what an Event reports
+type Kind string
+
+const (
+	StepStarted     Kind = "step_started"
+	CommandStarted  Kind = "command_started"
+	OutputLine      Kind = "output_line"
+	StepFinished    Kind = "step_finished"
+	PromptRequested Kind = "prompt_requested"
+)
+
+// Output streams of an OutputLine event
+const (
+	StreamLog    = "log"
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// Log levels of an OutputLine event on the log stream
+const (
+	LevelPlain   = ""
+	LevelStep    = "step"
+	LevelInfo    = "info"
+	LevelSuccess = "success"
+	LevelWarning = "warning"
+	LevelError   = "error"
+)
+
+// Event is one progress report of a run
+type Event struct {
+	Kind Kind      `json:"kind"`
+	Time time.Time `json:"time"`
+	// Step is the ID of the step the event belongs to; empty outside of steps
+	Step  string `json:"step,omitempty"`
+	Title string `json:"title,omitempty"`
+	Icon  string `json:"icon,omitempty"`
+	// Command is the command line of a CommandStarted event, with secrets masked
+	Command string `json:"command,omitempty"`
+	Stream  string `json:"stream,omitempty"`
+	Level   string `json:"level,omitempty"`
+	Line    string `json:"line,omitempty"`
+	// Status, Error and Hint describe the outcome of a StepFinished event
+	Status string `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+	Hint   string `json:"hint,omitempty"`
+	// Prompt is the question of a PromptRequested event
+	Prompt string `json:"prompt,omitempty"`
+}
+
+// Sink consumes events. Handle is called without the bus locked and may run for several publishers
+// at once, so it must be safe for concurrent use; a sink that blocks only holds up its publisher.
+type Sink interface {
+	Handle(ev Event)
+}
+
+// SinkFunc adapts a function to a Sink
+type SinkFunc func(ev Event)
+
+func (f SinkFunc) Handle(ev Event) { f(ev) }
+
+// Bus delivers published events to every subscribed sink in order.
+// It remembers the running step so events published during a step are attributed to it.
+type Bus struct {
+	mu      sync.Mutex
+	sinks   []*subscription
+	current string
+}
+
+type subscription struct {
+	sink Sink
+}
+
+// Subscribe adds a sink and returns a function that removes it again
+func (b *Bus) Subscribe(sink Sink) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := &subscription{sink: sink}
+	b.sinks = append(b.sinks, sub)
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for i, s := range b.sinks {
+			if s == sub {
+				b.sinks = append(b.sinks[:i:i], b.sinks[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish stamps ev with the time and the running step and hands it to every sink
+func (b *Bus) Publish(ev Event) {
+	b.mu.Lock()
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	if ev.Kind == StepStarted {
+		b.current = ev.Step
+	}
+	if ev.Step == "" {
+		ev.Step = b.current
+	}
+	if ev.Kind == StepFinished {
+		b.current = ""
+	}
+	sinks := make([]Sink, len(b.sinks))
+	for i, s := range b.sinks {
+		sinks[i] = s.sink
+	}
+	b.mu.Unlock()
+
+	// Sinks run outside the lock, so a slow sink cannot stall other publishers or subscribers
+	for _, sink := range sinks {
+		sink.Handle(ev)
+	}
+}
+
+// bus is the process-wide bus used by the package-level functions
+var bus = &Bus{}
+
+// Subscribe adds a sink to the process-wide bus and returns a function that removes it again
+func Subscribe(sink Sink) func() {
+	return bus.Subscribe(sink)
+}
+
+// Publish sends ev to the sinks of the process-wide bus
+func Publish(ev Event) {
+	bus.Publish(ev)
+}
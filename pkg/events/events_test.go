@@ -0,0 +1,62 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPublishDoesNotHoldTheBusWhileSinksRun(t *testing.T) {
+	var b Bus
+	release := make(chan struct{})
+	blocked := make(chan struct{})
+	b.Subscribe(SinkFunc(func(ev Event) {
+		if ev.Line == "block" {
+			close(blocked)
+			<-release
+		}
+	}))
+	got := make(chan Event, 2)
+	b.Subscribe(SinkFunc(func(ev Event) { got <- ev }))
+
+	go b.Publish(Event{Kind: OutputLine, Line: "block"})
+	<-blocked
+
+	// Another publisher and a new subscriber get through while the first sink is stuck
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		b.Publish(Event{Kind: StepStarted, Step: "packages"})
+		b.Subscribe(SinkFunc(func(Event) {}))()
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Publish blocked behind a slow sink")
+	}
+	if ev := <-got; ev.Kind != StepStarted {
+		t.Errorf("second sink got %+v first, want the step_started event", ev)
+	}
+	close(release)
+}
+
+func TestPublishAttributesEventsToTheRunningStep(t *testing.T) {
+	var b Bus
+	var got []Event
+	b.Subscribe(SinkFunc(func(ev Event) { got = append(got, ev) }))
+
+	b.Publish(Event{Kind: OutputLine, Line: "before"})
+	b.Publish(Event{Kind: StepStarted, Step: "packages"})
+	b.Publish(Event{Kind: OutputLine, Line: "during"})
+	b.Publish(Event{Kind: StepFinished, Step: "packages"})
+	b.Publish(Event{Kind: OutputLine, Line: "after"})
+
+	want := []string{"", "packages", "packages", "packages", ""}
+	for i, ev := range got {
+		if ev.Step != want[i] {
+			t.Errorf("event %d (%s %q) has step %q, want %q", i, ev.Kind, ev.Line, ev.Step, want[i])
+		}
+		if ev.Time.IsZero() {
+			t.Errorf("event %d has no time", i)
+		}
+	}
+}
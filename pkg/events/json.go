@@ -0,0 +1,26 @@
+package events
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// JSONSink writes every event as one JSON object per line
+type JSONSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONSink returns a sink writing JSON lines to w
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{enc: json.NewEncoder(w)}
+}
+
+func (s *JSONSink) Handle(ev Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// A broken stream must not break the run
+	_ = s.enc.Encode(ev)
+}
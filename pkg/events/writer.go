@@ -0,0 +1,52 @@
+package events
+
+import (
+	"bytes"
+	"sync"
+)
+
+// Writer publishes everything written to it as OutputLine events, one per line.
+// Carriage returns end a line too so progress bars don't pile up.
+type Writer struct {
+	mu     sync.Mutex
+	buf    []byte
+	stream string
+}
+
+// NewWriter returns a Writer publishing to the process-wide bus on the given stream
+func NewWriter(stream string) *Writer {
+	return &Writer{stream: stream}
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexAny(w.buf, "\r\n")
+		if i < 0 {
+			break
+		}
+		if line := string(w.buf[:i]); line != "" {
+			w.publish(line)
+		}
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush publishes any trailing partial line
+func (w *Writer) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) > 0 {
+		w.publish(string(w.buf))
+		w.buf = nil
+	}
+}
+
+func (w *Writer) publish(line string) {
+	Publish(Event{Kind: OutputLine, Stream: w.stream, Line: line})
+}
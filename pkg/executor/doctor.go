@@ -0,0 +1,216 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/whexy/wenxuan-dev-init/pkg/installer"
+	"github.com/whexy/wenxuan-dev-init/pkg/logger"
+	"github.com/whexy/wenxuan-dev-init/pkg/profile"
+)
+
+// Check statuses
+const (
+	CheckPass = "pass"
+	CheckWarn = "warn"
+	CheckFail = "fail"
+	CheckSkip = "skip"
+)
+
+// ErrChecksFailed is returned by Doctor when at least one check failed
+var ErrChecksFailed = errors.New("health checks failed")
+
+// Check is the outcome of one health probe
+type Check struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	// Fix suggests how to resolve a warning or failure
+	Fix string `json:"fix,omitempty"`
+}
+
+// requiredGitHubScopes are the classic token scopes gh needs to log in and push over HTTPS
+var requiredGitHubScopes = []string{"repo", "read:org"}
+
+// Doctor probes every integration setup configures and reports how healthy each one is
+func Doctor(prof *profile.Profile) ([]Check, error) {
+	var checks []Check
+	checks = append(checks, check1Password(prof)...)
+	checks = append(checks, checkGitHub(), checkGitCredentials(), checkChezmoi(prof), checkTailscale())
+
+	for _, c := range checks {
+		if c.Status == CheckFail {
+			return checks, ErrChecksFailed
+		}
+	}
+	return checks, nil
+}
+
+// rerunFix is the command that runs setup again for just one option; --only needs --non-interactive
+func rerunFix(option string) string {
+	return "wenxuan-dev-init --non-interactive --only " + option
+}
+
+func check1Password(prof *profile.Profile) []Check {
+	secrets := []struct{ name, ref, option string }{
+		{"1Password: GitHub token", prof.Secrets.GitHubToken, "secrets.github_token (--github-token)"},
+		{"1Password: Tailscale auth key", prof.Secrets.TailscaleAuthKey, "secrets.tailscale_authkey (--tailscale-authkey)"},
+	}
+
+	signIn := Check{Name: "1Password sign-in"}
+	if !installer.IsCommandAvailable("op") {
+		signIn.Status, signIn.Detail, signIn.Fix = CheckSkip, "op is not installed", rerunFix("install_1password")
+	} else if account, err := installer.OPWhoami(); err != nil {
+		signIn.Status, signIn.Detail = CheckFail, firstLine(err.Error())
+		signIn.Fix = "op signin, or export OP_SERVICE_ACCOUNT_TOKEN for a service account"
+	} else if account.UserType == "SERVICE_ACCOUNT" {
+		signIn.Status, signIn.Detail = CheckPass, "service account on "+account.URL
+	} else {
+		signIn.Status, signIn.Detail = CheckPass, fmt.Sprintf("signed in as %s on %s", account.Email, account.URL)
+	}
+
+	checks := []Check{signIn}
+	for _, s := range secrets {
+		c := Check{Name: s.name}
+		if signIn.Status != CheckPass {
+			c.Status, c.Detail = CheckSkip, "1Password is not signed in"
+		} else if err := installer.CheckSecret(s.ref); err != nil {
+			c.Status, c.Detail = CheckFail, fmt.Sprintf("%s: %s", s.ref, firstLine(err.Error()))
+			c.Fix = "check that the item exists and the account can read it, or point " + s.option + " at the right reference"
+		} else {
+			c.Status, c.Detail = CheckPass, s.ref+" is readable"
+		}
+		checks = append(checks, c)
+	}
+	return checks
+}
+
+func checkGitHub() Check {
+	c := Check{Name: "GitHub CLI"}
+	if !installer.IsCommandAvailable("gh") {
+		c.Status, c.Detail, c.Fix = CheckSkip, "gh is not installed", rerunFix("install_gh")
+		return c
+	}
+
+	auth, err := installer.GitHubAuthStatus()
+	if err != nil {
+		c.Status, c.Detail, c.Fix = CheckFail, firstLine(err.Error()), rerunFix("setup_github")
+		return c
+	}
+
+	c.Status, c.Detail = CheckPass, "logged in as "+auth.Account
+	if len(auth.Scopes) == 0 {
+		return c
+	}
+	var missing []string
+	for _, scope := range requiredGitHubScopes {
+		if !slices.Contains(auth.Scopes, scope) {
+			missing = append(missing, scope)
+		}
+	}
+	if len(missing) > 0 {
+		c.Status = CheckWarn
+		c.Detail = fmt.Sprintf("logged in as %s, but the token is missing %s", auth.Account, strings.Join(missing, ", "))
+		c.Fix = "gh auth refresh --scopes " + strings.Join(missing, ",") + ", or store a token with these scopes in 1Password"
+	}
+	return c
+}
+
+func checkGitCredentials() Check {
+	c := Check{Name: "git credential helper"}
+	if !installer.IsCommandAvailable("git") {
+		c.Status, c.Detail, c.Fix = CheckSkip, "git is not installed", rerunFix("install_git")
+		return c
+	}
+
+	helper, err := installer.GitCredentialHelper()
+	switch {
+	case err != nil:
+		c.Status, c.Detail = CheckFail, firstLine(err.Error())
+	case helper == "":
+		c.Status, c.Detail, c.Fix = CheckWarn, "no credential helper for github.com", "gh auth setup-git"
+	case strings.Contains(helper, "gh auth git-credential"):
+		c.Status, c.Detail = CheckPass, "github.com uses gh"
+	default:
+		c.Status, c.Detail = CheckPass, "github.com uses "+helper
+	}
+	return c
+}
+
+func checkChezmoi(prof *profile.Profile) Check {
+	c := Check{Name: "chezmoi"}
+	if !installer.IsCommandAvailable("chezmoi") {
+		c.Status, c.Detail, c.Fix = CheckSkip, "chezmoi is not installed", rerunFix("install_chezmoi")
+		return c
+	}
+
+	initialized, changed, err := installer.ChezmoiStatus()
+	switch {
+	case !initialized:
+		c.Status, c.Detail, c.Fix = CheckWarn, "no source directory", "chezmoi init --apply "+prof.Dotfiles.Repo
+	case err != nil:
+		c.Status, c.Detail = CheckFail, firstLine(err.Error())
+	case len(changed) > 0:
+		shown := changed
+		if len(shown) > 3 {
+			shown = append(slices.Clone(shown[:3]), "...")
+		}
+		c.Status, c.Detail = CheckWarn, fmt.Sprintf("%d targets differ from the source state: %s", len(changed), strings.Join(shown, ", "))
+		c.Fix = "chezmoi diff to review, then chezmoi apply"
+	default:
+		c.Status, c.Detail = CheckPass, "targets match the source state"
+	}
+	return c
+}
+
+func checkTailscale() Check {
+	c := Check{Name: "Tailscale"}
+	if !installer.IsCommandAvailable("tailscale") {
+		c.Status, c.Detail, c.Fix = CheckSkip, "tailscale is not installed", rerunFix("install_tailscale")
+		return c
+	}
+
+	st, err := installer.TailscaleStatus()
+	switch {
+	case err != nil:
+		c.Status, c.Detail, c.Fix = CheckFail, firstLine(err.Error()), "start tailscaled, e.g. sudo systemctl enable --now tailscaled"
+	case st.BackendState == "Running":
+		c.Status, c.Detail = CheckPass, fmt.Sprintf("connected as %s (%s)", st.HostName, strings.Join(st.IPs, ", "))
+	case st.BackendState == "Stopped":
+		c.Status, c.Detail, c.Fix = CheckWarn, "logged in but disconnected", "tailscale up"
+	default:
+		c.Status, c.Detail, c.Fix = CheckFail, "state "+st.BackendState, rerunFix("setup_tailscale")
+	}
+	return c
+}
+
+// checkIcons are the report icons of each check status
+var checkIcons = map[string]string{
+	CheckPass: "✅",
+	CheckWarn: "⚠️ ",
+	CheckFail: "❌",
+	CheckSkip: "⏭️ ",
+}
+
+// PrintDoctorReport prints one line per check, with the fix below warnings and failures
+func PrintDoctorReport(checks []Check) {
+	width := 0
+	for _, c := range checks {
+		width = max(width, len(c.Name))
+	}
+
+	logger.Println("")
+	logger.Step("🩺", "Health checks")
+	counts := make(map[string]int)
+	for _, c := range checks {
+		counts[c.Status]++
+		logger.Println(fmt.Sprintf("  %s %-*s  %s", checkIcons[c.Status], width, c.Name, c.Detail))
+		if c.Fix != "" && (c.Status == CheckWarn || c.Status == CheckFail) {
+			logger.Println(fmt.Sprintf("     %-*s  → %s", width, "", c.Fix))
+		}
+	}
+	logger.Println("")
+	logger.Println(fmt.Sprintf("%d passed, %d warnings, %d failed, %d skipped", counts[CheckPass], counts[CheckWarn], counts[CheckFail], counts[CheckSkip]))
+}
@@ -0,0 +1,259 @@
+package executor_test
+
+import (
+	"errors"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/whexy/wenxuan-dev-init/pkg/executor"
+	"github.com/whexy/wenxuan-dev-init/pkg/installer"
+	"github.com/whexy/wenxuan-dev-init/pkg/profile"
+)
+
+// options turns a list of option keys into the selection map executor.New takes
+func options(keys ...string) map[string]bool {
+	m := make(map[string]bool)
+	for _, key := range keys {
+		m[key] = true
+	}
+	return m
+}
+
+var (
+	allPackages = []string{"install_git", "install_gh", "install_1password", "install_chezmoi"}
+	allSetup    = []string{"login_1password", "setup_github", "init_chezmoi"}
+)
+
+func TestExecuteCommands(t *testing.T) {
+	tests := []struct {
+		name    string
+		manager string
+		options map[string]bool
+		// machine sets up the shims; it defaults to a fresh machine
+		machine func(h *harness, manager string)
+		want    []string
+		wantErr error
+		// downloads are the URLs fetched over HTTP
+		downloads []string
+	}{
+		{
+			name:    "apt everything",
+			manager: "apt",
+			options: options(append(append(allPackages, allSetup...), "install_tailscale", "setup_tailscale")...),
+			want: []string{
+				"dd of=/usr/share/keyrings/githubcli-archive-keyring.gpg status=none",
+				"tee /etc/apt/sources.list.d/github-cli.list",
+				"gpg --dearmor --yes --output /usr/share/keyrings/1password-archive-keyring.gpg",
+				"tee /etc/apt/sources.list.d/1password.list",
+				"apt-get update",
+				"apt-get install -y git gh 1password-cli",
+				"sh -s -- -b /usr/local/bin",
+				"dd of=/usr/share/keyrings/tailscale-archive-keyring.gpg status=none",
+				"tee /etc/apt/sources.list.d/tailscale.list",
+				"apt-get update",
+				"apt-get install -y tailscale",
+				"op whoami",
+				"gh auth status",
+				"op read op://Developer/GitHub Personal Access Token/token",
+				"gh auth login --with-token",
+				"gh auth setup-git",
+				"chezmoi init --apply whexy --no-tty --promptDefaults",
+				"tailscale status --json",
+				"op read op://Developer/tailscale auth key/credential",
+				"tailscale up --authkey secret-for-op://Developer/tailscale auth key/credential",
+			},
+			downloads: []string{
+				"https://cli.github.com/packages/githubcli-archive-keyring.gpg",
+				"https://downloads.1password.com/linux/keys/1password.asc",
+				"https://get.chezmoi.io",
+				"https://pkgs.tailscale.com/stable/ubuntu/noble.noarmor.gpg",
+			},
+		},
+		{
+			name:    "apt packages only",
+			manager: "apt",
+			options: options("install_git", "install_gh"),
+			want: []string{
+				"dd of=/usr/share/keyrings/githubcli-archive-keyring.gpg status=none",
+				"tee /etc/apt/sources.list.d/github-cli.list",
+				"apt-get update",
+				"apt-get install -y git gh",
+			},
+			downloads: []string{"https://cli.github.com/packages/githubcli-archive-keyring.gpg"},
+		},
+		{
+			name:    "apt repository already added",
+			manager: "apt",
+			options: options("install_git", "install_gh"),
+			machine: func(h *harness, manager string) {
+				h.freshMachine(manager)
+				h.writeFile("/etc/apt/sources.list.d/github-cli.list", "deb https://cli.github.com/packages stable main\n")
+			},
+			want: []string{
+				"apt-get update",
+				"apt-get install -y git gh",
+			},
+		},
+		{
+			name:    "already configured",
+			manager: "apt",
+			options: options(append(allSetup, "install_tailscale", "setup_tailscale")...),
+			machine: (*harness).configuredMachine,
+			want: []string{
+				"op whoami",
+				"gh auth status",
+				"chezmoi init --apply whexy --no-tty --promptDefaults",
+				"tailscale status --json",
+			},
+		},
+		{
+			name:    "apt install fails",
+			manager: "apt",
+			options: options(append(allPackages, allSetup...)...),
+			machine: func(h *harness, manager string) {
+				h.freshMachine(manager)
+				h.shim("apt-get", `if [ "$1" = install ]; then exit 100; fi`)
+			},
+			want: []string{
+				"dd of=/usr/share/keyrings/githubcli-archive-keyring.gpg status=none",
+				"tee /etc/apt/sources.list.d/github-cli.list",
+				"gpg --dearmor --yes --output /usr/share/keyrings/1password-archive-keyring.gpg",
+				"tee /etc/apt/sources.list.d/1password.list",
+				"apt-get update",
+				"apt-get install -y git gh 1password-cli",
+			},
+			wantErr:   executor.ErrTotalFailure,
+			downloads: []string{"https://cli.github.com/packages/githubcli-archive-keyring.gpg", "https://downloads.1password.com/linux/keys/1password.asc"},
+		},
+		{
+			name:    "dnf",
+			manager: "dnf",
+			options: options(append(allPackages, allSetup...)...),
+			want: []string{
+				"dnf config-manager --help",
+				"dnf config-manager --add-repo https://cli.github.com/packages/rpm/gh-cli.repo",
+				"rpm --import https://downloads.1password.com/linux/keys/1password.asc",
+				"tee /etc/yum.repos.d/1password.repo",
+				"dnf install -y git gh 1password-cli chezmoi",
+				"op whoami",
+				"gh auth status",
+				"op read op://Developer/GitHub Personal Access Token/token",
+				"gh auth login --with-token",
+				"gh auth setup-git",
+				"chezmoi init --apply whexy --no-tty --promptDefaults",
+			},
+		},
+		{
+			name:    "yum",
+			manager: "yum",
+			options: options(append(allPackages, allSetup...)...),
+			want: []string{
+				"tee /etc/yum.repos.d/gh-cli.repo",
+				"rpm --import https://downloads.1password.com/linux/keys/1password.asc",
+				"tee /etc/yum.repos.d/1password.repo",
+				"yum install -y git gh 1password-cli chezmoi",
+				"op whoami",
+				"gh auth status",
+				"op read op://Developer/GitHub Personal Access Token/token",
+				"gh auth login --with-token",
+				"gh auth setup-git",
+				"chezmoi init --apply whexy --no-tty --promptDefaults",
+			},
+			downloads: []string{"https://cli.github.com/packages/rpm/gh-cli.repo"},
+		},
+		{
+			name:    "zypper",
+			manager: "zypper",
+			options: options("install_git", "install_gh", "install_chezmoi", "install_tailscale", "init_chezmoi"),
+			want: []string{
+				"rpm --import https://cli.github.com/packages/githubcli-archive-keyring.asc",
+				"zypper --non-interactive addrepo https://cli.github.com/packages/rpm/gh-cli.repo",
+				"zypper --non-interactive refresh gh-cli",
+				"zypper --non-interactive install --auto-agree-with-licenses git gh chezmoi",
+				"zypper --non-interactive refresh",
+				"zypper --non-interactive install --auto-agree-with-licenses tailscale",
+				"systemctl enable --now tailscaled",
+				"chezmoi init --apply whexy --no-tty --promptDefaults",
+			},
+		},
+		{
+			name:    "pacman",
+			manager: "pacman",
+			options: options("install_git", "install_gh", "install_chezmoi", "install_tailscale", "init_chezmoi"),
+			want: []string{
+				"pacman -Syu --needed --noconfirm git github-cli chezmoi",
+				"pacman -Syu --needed --noconfirm tailscale",
+				"systemctl enable --now tailscaled",
+				"chezmoi init --apply whexy --no-tty --promptDefaults",
+			},
+		},
+		{
+			name:    "brew",
+			manager: "brew",
+			options: options("install_git", "install_gh", "install_chezmoi", "init_chezmoi"),
+			want: []string{
+				"brew install --formula git gh chezmoi",
+				"chezmoi init --apply whexy --no-tty --promptDefaults",
+			},
+		},
+		{
+			name:    "devbox",
+			manager: "devbox",
+			options: options(append(allPackages, allSetup...)...),
+			want: []string{
+				"devbox global add git gh _1password-cli chezmoi",
+				"op whoami",
+				"gh auth status",
+				"op read op://Developer/GitHub Personal Access Token/token",
+				"gh auth login --with-token",
+				"gh auth setup-git",
+				"chezmoi init --apply whexy --no-tty --promptDefaults",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHarness(t)
+			machine := tt.machine
+			if machine == nil {
+				machine = (*harness).freshMachine
+			}
+			machine(h, tt.manager)
+			// devbox is not a system package manager; it is picked because it is on PATH
+			if tt.manager != "devbox" {
+				if err := installer.SetPreferredPackageManager(tt.manager); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			e := executor.New(tt.options, profile.Default())
+			e.SetNonInteractive(true)
+			err := e.Execute()
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Execute() error = %v, want %v", err, tt.wantErr)
+			}
+			assertCalls(t, h.calls(), tt.want)
+			if !slices.Equal(h.downloads(), tt.downloads) {
+				t.Errorf("downloads = %v, want %v", h.downloads(), tt.downloads)
+			}
+		})
+	}
+}
+
+func TestExecuteCriticalFailureIsTotal(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("on macOS a missing package manager means installing Homebrew")
+	}
+	h := newHarness(t)
+	h.freshMachine("")
+
+	e := executor.New(options("install_git", "init_chezmoi"), profile.Default())
+	e.SetNonInteractive(true)
+	err := e.Execute()
+	if !errors.Is(err, executor.ErrTotalFailure) || !strings.Contains(err.Error(), "no supported package manager found") {
+		t.Errorf("Execute() error = %v, want a total failure from the package manager step", err)
+	}
+}
@@ -1,10 +1,17 @@
 package executor
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"slices"
 
+	"github.com/whexy/wenxuan-dev-init/pkg/events"
 	"github.com/whexy/wenxuan-dev-init/pkg/installer"
 	"github.com/whexy/wenxuan-dev-init/pkg/logger"
+	"github.com/whexy/wenxuan-dev-init/pkg/profile"
+	"github.com/whexy/wenxuan-dev-init/pkg/state"
 	"github.com/whexy/wenxuan-dev-init/pkg/ui"
 )
 
@@ -20,16 +27,48 @@ type Config struct {
 	SetupGitHub      bool
 	InitChezmoi      bool
 	SetupTailscale   bool
+	DotfilesRepo     string
+	ExtraPackages    []string
+
+	// NonInteractive disables every prompt; questions are answered by policy
+	NonInteractive bool
+	// OnDevboxFailure decides what happens when devbox fails: "ask", "system" or "abort"
+	OnDevboxFailure string
 }
 
+// Devbox failure policies
+const (
+	DevboxFailureAsk    = "ask"
+	DevboxFailureSystem = "system"
+	DevboxFailureAbort  = "abort"
+)
+
+var (
+	// ErrAborted is returned when the run stops because the user or policy declined to continue
+	ErrAborted = errors.New("setup aborted")
+	// ErrRerunRequired is returned when devbox was installed but its environment could not be loaded into the run
+	ErrRerunRequired = errors.New("devbox installed - please reload your shell and rerun")
+)
+
 // Executor handles the execution workflow
 type Executor struct {
-	config Config
-	pkgMgr installer.PackageManager
+	config  Config
+	pkgMgr  installer.PackageManager
+	results []Result
+	state   *state.State
+
+	// devboxEnv is set once the devbox global environment was loaded into this process
+	devboxEnv   bool
+	hookOffered bool
 }
 
-// New creates a new Executor with the given configuration
-func New(options map[string]bool) *Executor {
+// New creates a new Executor with the given selections and profile.
+// Use SetNonInteractive and SetDevboxFailurePolicy to adjust prompting before Execute.
+func New(options map[string]bool, prof *profile.Profile) *Executor {
+	if prof == nil {
+		prof = profile.Default()
+	}
+
 	return &Executor{
 		config: Config{
 			InstallDevbox:    options["install_devbox"],
@@ -42,147 +81,344 @@ func New(options map[string]bool) *Executor {
 			SetupGitHub:      options["setup_github"],
 			InitChezmoi:      options["init_chezmoi"],
 			SetupTailscale:   options["setup_tailscale"],
+			DotfilesRepo:     prof.Dotfiles.Repo,
+			ExtraPackages:    prof.Packages,
+			OnDevboxFailure:  DevboxFailureAsk,
 		},
 	}
 }
 
+// SetNonInteractive disables prompts for the run
+func (e *Executor) SetNonInteractive(nonInteractive bool) {
+	e.config.NonInteractive = nonInteractive
+}
+
+// SetDevboxFailurePolicy sets how devbox failures are handled
+func (e *Executor) SetDevboxFailurePolicy(policy string) error {
+	switch policy {
+	case DevboxFailureAsk, DevboxFailureSystem, DevboxFailureAbort:
+		e.config.OnDevboxFailure = policy
+		return nil
+	}
+	return fmt.Errorf("unknown devbox failure policy %q (want ask, system or abort)", policy)
+}
+
+// SetState makes the run persist its progress to st.
+// Steps that st lists as completed are reported as already done without running again.
+func (e *Executor) SetState(st *state.State) {
+	e.state = st
+}
+
+// fallbackToSystem decides whether to switch to the system package manager after a devbox failure
+func (e *Executor) fallbackToSystem(question string) bool {
+	switch e.config.OnDevboxFailure {
+	case DevboxFailureSystem:
+		logger.Info("Policy: falling back to the system package manager")
+		return true
+	case DevboxFailureAbort:
+		logger.Info("Policy: not falling back to the system package manager")
+		return false
+	}
+
+	if e.config.NonInteractive {
+		logger.Info("Non-interactive mode: not falling back to the system package manager")
+		return false
+	}
+	return ui.AskYesNo(question)
+}
+
+// Plan records what Execute would do for the current selections without changing the system
+func (e *Executor) Plan() (*installer.Plan, error) {
+	plan := installer.StartPlan()
+	defer installer.StopPlan()
+
+	// Nothing is executed, so progress output would only be noise
+	defer logger.SetOutput(logger.Output())
+	logger.SetOutput(io.Discard)
+
+	err := e.Execute()
+	// Steps that would be skipped or fail are recorded as plan notes, not planning errors,
+	// but a critical step that cannot even be planned is
+	var critical *criticalFailure
+	if errors.As(err, &critical) {
+		err = fmt.Errorf("%s failed: %w", lowerFirst(critical.title), critical.err)
+	} else if errors.Is(err, ErrPartialFailure) || errors.Is(err, ErrTotalFailure) {
+		err = nil
+	}
+	if e.pkgMgr != nil {
+		plan.PackageManager = e.pkgMgr.Name()
+	}
+	return plan, err
+}
+
+// step announces a workflow step; in plan mode it starts a new plan section
+func (e *Executor) step(icon, message string) {
+	logger.Step(icon, message)
+	installer.BeginPlanStep(message)
+}
+
+// warn logs a warning and records it in the plan
+func (e *Executor) warn(message string) {
+	logger.Warning(message)
+	installer.PlanNote(message)
+}
+
+// fail logs an error and records it in the plan
+func (e *Executor) fail(message string) {
+	logger.Error(message)
+	installer.PlanNote(message)
+}
+
 // Execute runs the complete setup workflow
 func (e *Executor) Execute() error {
 	logger.Step("🚀", "Starting setup process...")
-	logger.Println("")
 
-	// Step 1: Setup package manager
-	if err := e.setupPackageManager(); err != nil {
-		return fmt.Errorf("failed to setup package manager: %w", err)
+	graph, err := NewGraph(e.resume(e.steps())...)
+	if err != nil {
+		return fmt.Errorf("invalid setup graph: %w", err)
 	}
 
-	// Step 2: Install packages
-	if err := e.installPackages(); err != nil {
-		return fmt.Errorf("failed to install packages: %w", err)
-	}
+	e.results = graph.Run(stepPublisher{e})
+	PrintSummary(e.results)
 
-	// Step 2.5: Install Tailscale (requires system package manager for systemd)
-	if e.config.InstallTailscale {
-		if err := e.installTailscale(); err != nil {
-			logger.Error(fmt.Sprintf("Tailscale installation failed: %v", err))
-			logger.Warning("You can install Tailscale manually later.")
-			// Don't fail the entire setup, just continue
+	critical := make(map[string]bool)
+	for _, step := range graph.Steps() {
+		critical[step.ID()] = step.Critical()
+	}
+	for _, res := range e.results {
+		if res.Status == StatusFailed && critical[res.ID] {
+			return &criticalFailure{title: res.Title, err: res.Err}
 		}
 	}
 
-	// Step 3: Authenticate 1Password
-	if e.config.Login1Password {
-		// Check if 1Password CLI is available
-		if !installer.IsCommandAvailable("op") {
-			logger.Warning("1Password CLI not found, skipping authentication.")
-			logger.Println("Install 1Password CLI manually and run 'op signin' if needed.")
-		} else {
-			if err := e.authenticate1Password(); err != nil {
-				logger.Error(fmt.Sprintf("1Password authentication failed: %v", err))
-				logger.Warning("Continuing without 1Password authentication.")
-			}
+	if err := outcome(e.results, critical); err != nil {
+		return err
+	}
+
+	logger.Println("")
+	logger.Success("Setup complete! Your development environment is ready.")
+	return nil
+}
+
+// steps declares the setup workflow as a graph of steps
+func (e *Executor) steps() []Step {
+	return []Step{
+		&funcStep{
+			id:       "package-manager",
+			title:    "Setting up package manager",
+			icon:     "📦",
+			enabled:  true,
+			critical: true,
+			repeat:   true,
+			run:      e.setupPackageManager,
+		},
+		&funcStep{
+			id:        "packages",
+			title:     "Installing packages",
+			icon:      "📦",
+			dependsOn: []string{"package-manager"},
+			enabled:   e.wantsPackages(),
+			hint:      "Check the package manager output above and install the missing packages manually.",
+			run:       e.installPackages,
+			verify:    e.verifyPackages,
+		},
+		// Tailscale requires systemd and is always installed with the system package manager
+		&funcStep{
+			id:        "tailscale-install",
+			title:     "Installing Tailscale",
+			icon:      "🔗",
+			after:     []string{"packages"},
+			enabled:   e.config.InstallTailscale,
+			hint:      "You can install Tailscale manually later.",
+			satisfied: func() bool { return installer.IsCommandAvailable("tailscale") },
+			run:       e.installTailscale,
+			verify:    requireCommands("tailscale"),
+		},
+		&funcStep{
+			id:        "1password-login",
+			title:     "Logging in to 1Password",
+			icon:      "🔐",
+			after:     []string{"packages", "tailscale-install"},
+			enabled:   e.config.Login1Password,
+			hint:      "Install 1Password CLI if needed and run 'op signin'.",
+			check:     requireCommands("op"),
+			satisfied: installer.Is1PasswordSignedIn,
+			run:       e.authenticate1Password,
+		},
+		&funcStep{
+			id:        "github-auth",
+			title:     "Setting up GitHub authentication",
+			icon:      "🐙",
+			dependsOn: []string{"1password-login"},
+			after:     []string{"packages"},
+			enabled:   e.config.SetupGitHub,
+			hint:      "You can authenticate manually with 'gh auth login'.",
+			check:     requireCommands("gh", "op"),
+			satisfied: installer.IsGitHubAuthenticated,
+			run:       e.setupGitHub,
+		},
+		&funcStep{
+			id:      "chezmoi-init",
+			title:   "Initializing chezmoi",
+			icon:    "🏠",
+			after:   []string{"packages", "github-auth"},
+			enabled: e.config.InitChezmoi,
+			hint:    fmt.Sprintf("You can initialize manually with 'chezmoi init --apply %s'.", e.config.DotfilesRepo),
+			check:   requireCommands("chezmoi"),
+			run:     e.initializeChezmoi,
+		},
+		&funcStep{
+			id:        "tailscale-up",
+			title:     "Setting up Tailscale",
+			icon:      "🔗",
+			dependsOn: []string{"tailscale-install", "1password-login"},
+			after:     []string{"chezmoi-init"},
+			enabled:   e.config.SetupTailscale,
+			hint:      "You can setup manually with 'tailscale up --authkey YOUR_KEY'.",
+			check:     requireCommands("tailscale", "op"),
+			satisfied: installer.IsTailscaleSetup,
+			run:       e.setupTailscale,
+		},
+	}
+}
+
+// resume replaces the steps completed in an earlier run
+func (e *Executor) resume(steps []Step) []Step {
+	if e.state == nil {
+		return steps
+	}
+	for i, s := range steps {
+		if fs, ok := s.(*funcStep); ok && fs.repeat {
+			continue
+		}
+		if e.state.IsCompleted(s.ID()) {
+			steps[i] = completedStep{s}
 		}
 	}
+	return steps
+}
 
-	// Step 4: Setup GitHub
-	if e.config.SetupGitHub {
-		// Check if required tools are available
-		if !installer.IsCommandAvailable("gh") {
-			logger.Warning("GitHub CLI not found, skipping GitHub setup.")
-			logger.Println("Install GitHub CLI manually if needed.")
-		} else if !installer.IsCommandAvailable("op") {
-			logger.Warning("1Password CLI not found, skipping GitHub setup.")
-			logger.Println("You'll need to authenticate GitHub manually with 'gh auth login'.")
-		} else {
-			if err := e.setupGitHub(); err != nil {
-				logger.Error(fmt.Sprintf("GitHub setup failed: %v", err))
-				logger.Warning("You can authenticate manually with 'gh auth login'.")
+// commandNames gives readable names for the commands steps depend on
+var commandNames = map[string]string{
+	"gh":        "GitHub CLI",
+	"op":        "1Password CLI",
+	"chezmoi":   "Chezmoi",
+	"tailscale": "Tailscale",
+}
+
+// requireCommands returns a check that fails when any of the commands is missing
+func requireCommands(commands ...string) func() error {
+	return func() error {
+		for _, cmd := range commands {
+			if !installer.IsCommandAvailable(cmd) {
+				name := commandNames[cmd]
+				if name == "" {
+					name = cmd
+				}
+				return fmt.Errorf("%s not found", name)
 			}
 		}
+		return nil
 	}
+}
 
-	// Step 5: Initialize Chezmoi
-	if e.config.InitChezmoi {
-		// Check if chezmoi is available
-		if !installer.IsCommandAvailable("chezmoi") {
-			logger.Warning("Chezmoi not found, skipping initialization.")
-			logger.Println("Install chezmoi manually if needed.")
-		} else {
-			if err := e.initializeChezmoi(); err != nil {
-				logger.Error(fmt.Sprintf("Chezmoi initialization failed: %v", err))
-				logger.Warning("You can initialize manually with 'chezmoi init --apply whexy'.")
-			}
+// stepPublisher reports step progress as events
+type stepPublisher struct {
+	e *Executor
+}
+
+func (l stepPublisher) Started(s Step) {
+	events.Publish(events.Event{Kind: events.StepStarted, Step: s.ID(), Title: s.Title(), Icon: s.Icon()})
+	installer.BeginPlanStep(s.Title() + "...")
+
+	if l.e.state != nil {
+		if err := l.e.state.Begin(s.ID()); err != nil {
+			logger.Warning(fmt.Sprintf("Could not save run state: %v", err))
 		}
 	}
+}
 
-	// Step 6: Setup Tailscale
-	if e.config.SetupTailscale {
-		// Check if tailscale is available
-		if !installer.IsCommandAvailable("tailscale") {
-			logger.Warning("Tailscale not found, skipping setup.")
-			logger.Println("Install Tailscale manually if needed.")
-		} else if !installer.IsCommandAvailable("op") {
-			logger.Warning("1Password CLI not found, skipping Tailscale setup.")
-			logger.Println("You'll need to setup Tailscale manually with 'tailscale up'.")
-		} else {
-			if err := e.setupTailscale(); err != nil {
-				logger.Error(fmt.Sprintf("Tailscale setup failed: %v", err))
-				logger.Warning("You can setup manually with 'tailscale up --authkey YOUR_KEY'.")
-			}
+func (l stepPublisher) Finished(res Result) {
+	defer events.Publish(res.event())
+
+	if l.e.state != nil {
+		completed := res.Status == StatusSucceeded || res.Status == StatusAlreadyDone
+		if err := l.e.state.Finish(res.ID, completed); err != nil {
+			logger.Warning(fmt.Sprintf("Could not save run state: %v", err))
 		}
 	}
 
-	logger.Println("")
-	logger.Success("Setup complete! Your development environment is ready.")
-	return nil
+	switch res.Status {
+	case StatusAlreadyDone:
+		logger.Info("Already done, skipping.")
+		installer.PlanNote("Already done, skipping.")
+	case StatusMissingPrereq:
+		l.e.warn(fmt.Sprintf("%v, skipping.", res.Err))
+		if res.Hint != "" {
+			logger.Println(res.Hint)
+		}
+	case StatusFailed:
+		// The command output the error carries was just shown; the summary repeats it
+		l.e.fail(fmt.Sprintf("%s failed: %s", res.Title, firstLine(res.Err.Error())))
+		if res.Hint != "" {
+			l.e.warn(res.Hint)
+		}
+	}
 }
 
 func (e *Executor) setupPackageManager() error {
 	if e.config.InstallDevbox {
 		// Check if devbox is already available
 		if !installer.IsCommandAvailable("devbox") {
-			logger.Step("📦", "Installing devbox...")
+			e.step("📦", "Installing devbox...")
 			if err := installer.InstallDevbox(); err != nil {
-				logger.Error(fmt.Sprintf("Devbox installation failed: %v", err))
-				logger.Warning("Devbox installation encountered errors.")
+				e.fail(fmt.Sprintf("Devbox installation failed: %v", err))
+				e.warn("Devbox installation encountered errors.")
 
 				// Offer fallback
-				if ui.AskYesNo("Would you like to use the system package manager instead?") {
+				if e.fallbackToSystem("Would you like to use the system package manager instead?") {
 					logger.Info("Falling back to system package manager...")
 					var detectErr error
-					e.pkgMgr, detectErr = installer.DetectPackageManager()
+					e.pkgMgr, detectErr = e.withHomebrew(installer.DetectSystemPackageManager())
 					if detectErr != nil {
 						return fmt.Errorf("failed to detect system package manager: %w", detectErr)
 					}
 				} else {
-					return fmt.Errorf("setup cancelled by user")
+					return ErrAborted
 				}
 			} else {
 				logger.Success("Devbox installed successfully!")
-				logger.Println("")
-				logger.Info("⚠️  Devbox requires environment initialization.")
-				logger.Println("")
-				logger.Info("Please run the following commands to activate devbox:")
-				logger.Println("   eval \"$(devbox global shellenv --init-hook)\"")
-				logger.Println("")
-				logger.Info("Then rerun this program to continue the setup.")
-				logger.Println("")
-				return fmt.Errorf("devbox installed - please reload your shell and rerun")
+
+				// Continue in this run with the devbox environment instead of asking for a shell reload
+				if err := e.loadDevboxEnv(); err != nil {
+					e.warn(fmt.Sprintf("Could not load the devbox environment: %v", err))
+					logger.Println("")
+					logger.Info("Please run the following commands to activate devbox:")
+					logger.Println("   eval \"$(devbox global shellenv --init-hook)\"")
+					logger.Println("")
+					logger.Info("Then rerun this program; it offers to resume where this run stopped.")
+					logger.Println("")
+					return ErrRerunRequired
+				}
+				e.offerDevboxInitHook()
+				e.pkgMgr = installer.NewDevboxManager()
 			}
 		} else {
 			logger.Info("Devbox is already installed")
+			if err := e.loadDevboxEnv(); err != nil {
+				e.warn(fmt.Sprintf("Could not load the devbox environment: %v", err))
+			}
 			e.pkgMgr = installer.NewDevboxManager()
 		}
 	} else {
 		var err error
-		e.pkgMgr, err = installer.DetectPackageManager()
+		e.pkgMgr, err = e.withHomebrew(installer.DetectPackageManager())
 		if err != nil {
 			return err
 		}
 	}
 
 	logger.Info(fmt.Sprintf("Using package manager: %s", e.pkgMgr.Name()))
-	logger.Println("")
 
 	return nil
 }
@@ -194,57 +430,155 @@ func (e *Executor) installPackages() error {
 		return nil
 	}
 
-	logger.Step("📦", fmt.Sprintf("Installing packages: %v", packagesToInstall))
-	if err := e.pkgMgr.Install(packagesToInstall...); err != nil {
+	logger.Info(fmt.Sprintf("Packages: %v", packagesToInstall))
+	err := installer.InstallPackages(e.pkgMgr, packagesToInstall...)
+	if err != nil && e.pkgMgr.Name() == "devbox" {
 		logger.Println("") // Add spacing after error output
-		logger.Error(fmt.Sprintf("Package installation failed: %v", err))
+		e.fail(fmt.Sprintf("Package installation failed: %v", err))
+		e.warn("Devbox package installation failed (this is common in containers).")
 
 		// If using devbox and it failed, offer to fallback
-		if e.pkgMgr.Name() == "devbox" {
-			logger.Warning("Devbox package installation failed (this is common in containers).")
-
-			if ui.AskYesNo("Would you like to try with the system package manager instead?") {
-				logger.Info("Switching to system package manager...")
-
-				// Detect and switch to system package manager
-				systemPkgMgr, detectErr := installer.DetectPackageManager()
-				if detectErr != nil {
-					logger.Error(fmt.Sprintf("Failed to detect system package manager: %v", detectErr))
-					logger.Warning("You can install packages manually later.")
-					return nil
-				}
+		if !e.fallbackToSystem("Would you like to try with the system package manager instead?") {
+			return err
+		}
+		logger.Info("Switching to system package manager...")
 
-				e.pkgMgr = systemPkgMgr
-				logger.Info(fmt.Sprintf("Using package manager: %s", e.pkgMgr.Name()))
+		// Detect and switch to system package manager
+		systemPkgMgr, detectErr := e.withHomebrew(installer.DetectSystemPackageManager())
+		if detectErr != nil {
+			return fmt.Errorf("%w; failed to detect system package manager: %v", err, detectErr)
+		}
 
-				// Retry installation with system package manager
-				logger.Step("📦", fmt.Sprintf("Retrying installation: %v", packagesToInstall))
-				if retryErr := e.pkgMgr.Install(packagesToInstall...); retryErr != nil {
-					logger.Println("") // Add spacing
-					logger.Error(fmt.Sprintf("Installation failed again: %v", retryErr))
-					logger.Warning("You can install packages manually later.")
-					return nil
-				}
+		e.pkgMgr = systemPkgMgr
+		logger.Info(fmt.Sprintf("Using package manager: %s", e.pkgMgr.Name()))
 
-				logger.Success("Packages installed successfully with system package manager!")
-				return nil
-			}
+		// Retry installation with system package manager
+		e.step("📦", fmt.Sprintf("Retrying installation: %v", packagesToInstall))
+		if err = installer.InstallPackages(e.pkgMgr, packagesToInstall...); err != nil {
+			return fmt.Errorf("installation with %s failed as well: %w", e.pkgMgr.Name(), err)
 		}
 
-		logger.Warning("Some packages may not have been installed.")
-		logger.Println("Please check the error messages above for details.")
+		logger.Success("Packages installed successfully with system package manager!")
 		return nil
 	}
+	if err != nil {
+		return err
+	}
 	logger.Success("Packages installed successfully")
 
-	// Show devbox shell initialization hint
+	// New shells need the devbox environment too
 	if e.pkgMgr.Name() == "devbox" {
+		e.offerDevboxInitHook()
+	}
+
+	return nil
+}
+
+// withHomebrew installs Homebrew when package manager detection found it is the one to use but missing,
+// and detects again; other results are passed through
+func (e *Executor) withHomebrew(pkgMgr installer.PackageManager, err error) (installer.PackageManager, error) {
+	if !errors.Is(err, installer.ErrHomebrewMissing) {
+		return pkgMgr, err
+	}
+
+	e.step("🍺", "Installing Homebrew...")
+	script, sum, err := installer.FetchHomebrewInstaller()
+	if err != nil {
+		return nil, err
+	}
+	if !installer.IsPlanning() {
+		defer os.Remove(script)
+	}
+
+	switch {
+	case e.config.NonInteractive:
+	case installer.IsPlanning():
+		installer.PlanNote("Shows the Homebrew installer for review and asks before running it")
+	default:
+		logger.Info(fmt.Sprintf("Homebrew installer saved to %s (SHA-256 %s)", script, sum))
+		if ui.AskYesNo("Review the installer script before running it?") {
+			if err := installer.ShowFile(script); err != nil {
+				e.warn(fmt.Sprintf("Could not show the installer: %v", err))
+			}
+		}
+		if !ui.AskYesNo("Run the Homebrew installer?") {
+			return nil, ErrAborted
+		}
+	}
+
+	if err := installer.InstallHomebrew(script); err != nil {
+		return nil, err
+	}
+	logger.Success("Homebrew installed successfully!")
+	return installer.DetectSystemPackageManager()
+}
+
+// loadDevboxEnv applies the devbox global environment to this process
+func (e *Executor) loadDevboxEnv() error {
+	if err := installer.InitDevboxShell(); err != nil {
+		return err
+	}
+	e.devboxEnv = true
+	return nil
+}
+
+// offerDevboxInitHook offers once per run to add the devbox init hook to the user's shell rc file
+func (e *Executor) offerDevboxInitHook() {
+	if e.hookOffered {
+		return
+	}
+	e.hookOffered = true
+
+	rcFile, line, err := installer.DevboxInitHook()
+	if err != nil {
 		logger.Println("")
-		logger.Warning("Don't forget to initialize devbox shell:")
+		logger.Warning(fmt.Sprintf("Don't forget to initialize devbox shell (%v):", err))
 		logger.Println("   eval \"$(devbox global shellenv --init-hook)\"")
+		return
+	}
+	if installer.HasDevboxInitHook(rcFile) {
+		return
 	}
 
-	return nil
+	if !e.config.NonInteractive && installer.IsPlanning() {
+		installer.PlanNote(fmt.Sprintf("Asks whether to add the devbox init hook to %s", rcFile))
+		return
+	}
+	if e.config.NonInteractive || !ui.AskYesNo(fmt.Sprintf("Add the devbox init hook to %s?", rcFile)) {
+		logger.Println("")
+		logger.Warning(fmt.Sprintf("Don't forget to initialize devbox shell in %s:", rcFile))
+		logger.Println("   " + line)
+		return
+	}
+
+	if err := installer.AddDevboxInitHook(rcFile, line); err != nil {
+		e.warn(fmt.Sprintf("Could not update %s: %v", rcFile, err))
+		return
+	}
+	logger.Success(fmt.Sprintf("Added the devbox init hook to %s", rcFile))
+}
+
+// wantsPackages reports whether any package beyond devbox and tailscale was selected
+func (e *Executor) wantsPackages() bool {
+	return e.config.InstallGit || e.config.InstallGH || e.config.Install1Password ||
+		e.config.InstallChezmoi || len(e.config.ExtraPackages) > 0
+}
+
+// verifyPackages checks that the selected tools ended up on PATH.
+// Devbox packages only appear once its environment is loaded, so without it they are not checked.
+func (e *Executor) verifyPackages() error {
+	if e.pkgMgr.Name() == "devbox" && !e.devboxEnv {
+		return nil
+	}
+
+	// Extra packages are not checked; their commands often differ from their names
+	var commands []string
+	for _, pkg := range e.getPackagesToInstall() {
+		if !slices.Contains(e.config.ExtraPackages, pkg) {
+			commands = append(commands, installer.ToolBinary(pkg))
+		}
+	}
+	return requireCommands(commands...)()
 }
 
 func (e *Executor) getPackagesToInstall() []string {
@@ -259,17 +593,15 @@ func (e *Executor) getPackagesToInstall() []string {
 	}
 
 	if e.config.Install1Password {
-		if e.pkgMgr.Name() == "devbox" {
-			packages = append(packages, "_1password-cli")
-		} else {
-			packages = append(packages, "1password-cli")
-		}
+		packages = append(packages, "1password-cli")
 	}
 
 	if e.config.InstallChezmoi {
 		packages = append(packages, "chezmoi")
 	}
 
+	packages = append(packages, e.config.ExtraPackages...)
+
 	// Note: Tailscale is NOT included here because it requires systemd
 	// and must be installed via system package manager, not devbox.
 	// It's installed separately in installTailscale()
@@ -278,8 +610,6 @@ func (e *Executor) getPackagesToInstall() []string {
 }
 
 func (e *Executor) authenticate1Password() error {
-	logger.Println("")
-	logger.Step("🔐", "Logging in to 1Password...")
 	if err := installer.Login1Password(); err != nil {
 		return err
 	}
@@ -288,9 +618,6 @@ func (e *Executor) authenticate1Password() error {
 }
 
 func (e *Executor) setupGitHub() error {
-	logger.Println("")
-	logger.Step("🐙", "Setting up GitHub authentication...")
-
 	token, err := installer.GetGitHubTokenFrom1Password()
 	if err != nil {
 		return err
@@ -305,10 +632,7 @@ func (e *Executor) setupGitHub() error {
 }
 
 func (e *Executor) initializeChezmoi() error {
-	logger.Println("")
-	logger.Step("🏠", "Initializing chezmoi...")
-
-	if err := installer.InitChezmoi("whexy"); err != nil {
+	if err := installer.InitChezmoi(e.config.DotfilesRepo); err != nil {
 		return err
 	}
 
@@ -317,19 +641,16 @@ func (e *Executor) initializeChezmoi() error {
 }
 
 func (e *Executor) installTailscale() error {
-	logger.Println("")
-	logger.Step("🔗", "Installing Tailscale (requires system package manager)...")
-
 	// Always use system package manager for Tailscale since it needs systemd
-	systemPkgMgr, err := installer.DetectPackageManager()
+	systemPkgMgr, err := e.withHomebrew(installer.DetectSystemPackageManager())
 	if err != nil {
 		return fmt.Errorf("failed to detect system package manager: %w", err)
 	}
 
 	logger.Info(fmt.Sprintf("Using system package manager (%s) for Tailscale installation", systemPkgMgr.Name()))
 
-	if err := systemPkgMgr.Install("tailscale"); err != nil {
-		return fmt.Errorf("failed to install tailscale: %w", err)
+	if err := installer.InstallTailscale(systemPkgMgr); err != nil {
+		return err
 	}
 
 	logger.Success("Tailscale installed successfully")
@@ -337,9 +658,6 @@ func (e *Executor) installTailscale() error {
 }
 
 func (e *Executor) setupTailscale() error {
-	logger.Println("")
-	logger.Step("🔗", "Setting up Tailscale...")
-
 	if err := installer.SetupTailscale(); err != nil {
 		return err
 	}
@@ -0,0 +1,253 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/whexy/wenxuan-dev-init/pkg/events"
+)
+
+// Step is a unit of work in the setup graph
+type Step interface {
+	// ID uniquely identifies the step within a graph
+	ID() string
+	Title() string
+	Icon() string
+	// DependsOn lists steps that must not fail for this step to run.
+	// Dependencies that were not selected or were already satisfied count as met.
+	DependsOn() []string
+	// After lists steps that only need to be ordered before this one
+	After() []string
+	// Enabled reports whether the step was selected
+	Enabled() bool
+	// Critical steps stop the whole run when they fail
+	Critical() bool
+	// Check verifies preconditions; an error skips the step as missing prerequisites
+	Check() error
+	// Satisfied reports whether the step's outcome is already in place
+	Satisfied() bool
+	Run() error
+	// Verify checks postconditions after a successful Run
+	Verify() error
+	// Hint tells the user how to finish the step by hand
+	Hint() string
+}
+
+// Status is the outcome of a step
+type Status string
+
+const (
+	StatusSucceeded     Status = "succeeded"
+	StatusAlreadyDone   Status = "skipped-already-done"
+	StatusMissingPrereq Status = "skipped-missing-prereq"
+	StatusNotSelected   Status = "not-selected"
+	StatusFailed        Status = "failed"
+	StatusNotRun        Status = "not-run"
+)
+
+// Result records what happened to a step
+type Result struct {
+	ID     string
+	Title  string
+	Status Status
+	Err    error
+	Hint   string
+}
+
+// event converts the result into a StepFinished event
+func (res Result) event() events.Event {
+	ev := events.Event{Kind: events.StepFinished, Step: res.ID, Title: res.Title, Status: string(res.Status), Hint: res.Hint}
+	if res.Err != nil {
+		ev.Error = res.Err.Error()
+	}
+	return ev
+}
+
+// ResultFromEvent rebuilds a Result from a StepFinished event
+func ResultFromEvent(ev events.Event) Result {
+	res := Result{ID: ev.Step, Title: ev.Title, Status: Status(ev.Status), Hint: ev.Hint}
+	if ev.Error != "" {
+		res.Err = errors.New(ev.Error)
+	}
+	return res
+}
+
+// Graph orders steps by their dependencies and runs them
+type Graph struct {
+	steps []Step
+	byID  map[string]Step
+}
+
+// NewGraph builds a graph, rejecting duplicate IDs, unknown references and cycles
+func NewGraph(steps ...Step) (*Graph, error) {
+	g := &Graph{byID: make(map[string]Step)}
+	for _, s := range steps {
+		if _, dup := g.byID[s.ID()]; dup {
+			return nil, fmt.Errorf("duplicate step %q", s.ID())
+		}
+		g.byID[s.ID()] = s
+		g.steps = append(g.steps, s)
+	}
+
+	for _, s := range steps {
+		for _, ref := range references(s) {
+			if _, ok := g.byID[ref]; !ok {
+				return nil, fmt.Errorf("step %q references unknown step %q", s.ID(), ref)
+			}
+		}
+	}
+
+	ordered, err := g.order()
+	if err != nil {
+		return nil, err
+	}
+	g.steps = ordered
+	return g, nil
+}
+
+// Steps returns the steps in execution order
+func (g *Graph) Steps() []Step {
+	return g.steps
+}
+
+// order sorts steps topologically, keeping registration order among independent steps
+func (g *Graph) order() ([]Step, error) {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	var ordered []Step
+
+	var visit func(s Step, path []string) error
+	visit = func(s Step, path []string) error {
+		switch state[s.ID()] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle: %s", strings.Join(append(path, s.ID()), " -> "))
+		}
+		state[s.ID()] = visiting
+		for _, ref := range references(s) {
+			if err := visit(g.byID[ref], append(path, s.ID())); err != nil {
+				return err
+			}
+		}
+		state[s.ID()] = done
+		ordered = append(ordered, s)
+		return nil
+	}
+
+	for _, s := range g.steps {
+		if err := visit(s, nil); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// Run executes the steps in order and reports each outcome through the callbacks.
+// A failing critical step stops the run; the remaining steps are reported as not run.
+func (g *Graph) Run(r Reporter) []Result {
+	results := make(map[string]Result)
+	var ordered []Result
+	var halted *Result
+
+	record := func(res Result) {
+		results[res.ID] = res
+		ordered = append(ordered, res)
+		r.Finished(res)
+	}
+
+	for _, s := range g.steps {
+		res := Result{ID: s.ID(), Title: s.Title(), Hint: s.Hint()}
+
+		if halted != nil {
+			res.Status = StatusNotRun
+			res.Err = fmt.Errorf("run stopped after %s failed", halted.Title)
+			results[res.ID] = res
+			ordered = append(ordered, res)
+			continue
+		}
+
+		if !s.Enabled() {
+			res.Status = StatusNotSelected
+			results[res.ID] = res
+			ordered = append(ordered, res)
+			continue
+		}
+
+		r.Started(s)
+
+		if dep, ok := failedDependency(s, results); ok {
+			res.Status = StatusMissingPrereq
+			res.Err = fmt.Errorf("requires %s, which did not complete", lowerFirst(dep.Title))
+			record(res)
+			continue
+		}
+
+		if err := s.Check(); err != nil {
+			res.Status = StatusMissingPrereq
+			res.Err = err
+			record(res)
+			continue
+		}
+
+		if s.Satisfied() {
+			res.Status = StatusAlreadyDone
+			record(res)
+			continue
+		}
+
+		if err := s.Run(); err != nil {
+			res.Status = StatusFailed
+			res.Err = err
+		} else if err := s.Verify(); err != nil {
+			res.Status = StatusFailed
+			res.Err = fmt.Errorf("postcondition failed: %w", err)
+		} else {
+			res.Status = StatusSucceeded
+		}
+		record(res)
+
+		if res.Status == StatusFailed && s.Critical() {
+			halted = &res
+		}
+	}
+
+	return ordered
+}
+
+// references returns every step s must be ordered after
+func references(s Step) []string {
+	refs := append([]string{}, s.DependsOn()...)
+	return append(refs, s.After()...)
+}
+
+// failedDependency returns the first dependency of s that did not complete
+func failedDependency(s Step, results map[string]Result) (Result, bool) {
+	for _, id := range s.DependsOn() {
+		switch res := results[id]; res.Status {
+		case StatusSucceeded, StatusAlreadyDone, StatusNotSelected:
+		default:
+			return res, true
+		}
+	}
+	return Result{}, false
+}
+
+// Reporter receives step lifecycle notifications from Graph.Run
+type Reporter interface {
+	Started(s Step)
+	Finished(res Result)
+}
+
+// lowerFirst lowercases the first letter of a title so it reads well mid-sentence
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
@@ -0,0 +1,270 @@
+package executor
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+)
+
+// recorder is a Reporter that remembers which steps started and how each finished
+type recorder struct {
+	started  []string
+	finished []Result
+}
+
+func (r *recorder) Started(s Step)      { r.started = append(r.started, s.ID()) }
+func (r *recorder) Finished(res Result) { r.finished = append(r.finished, res) }
+
+// ids returns the IDs of steps in order
+func ids(steps []Step) []string {
+	var out []string
+	for _, s := range steps {
+		out = append(out, s.ID())
+	}
+	return out
+}
+
+// statuses maps each result's step to its status
+func statuses(results []Result) map[string]Status {
+	out := make(map[string]Status)
+	for _, res := range results {
+		out[res.ID] = res.Status
+	}
+	return out
+}
+
+func TestNewGraphRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []Step
+		want  string
+	}{
+		{
+			name:  "duplicate",
+			steps: []Step{&funcStep{id: "a"}, &funcStep{id: "a"}},
+			want:  `duplicate step "a"`,
+		},
+		{
+			name:  "unknown dependency",
+			steps: []Step{&funcStep{id: "a", dependsOn: []string{"b"}}},
+			want:  `step "a" references unknown step "b"`,
+		},
+		{
+			name:  "unknown after",
+			steps: []Step{&funcStep{id: "a", after: []string{"b"}}},
+			want:  `step "a" references unknown step "b"`,
+		},
+		{
+			name: "cycle",
+			steps: []Step{
+				&funcStep{id: "a", dependsOn: []string{"c"}},
+				&funcStep{id: "b", dependsOn: []string{"a"}},
+				&funcStep{id: "c", after: []string{"b"}},
+			},
+			want: "dependency cycle: a -> c -> b -> a",
+		},
+		{
+			name:  "self reference",
+			steps: []Step{&funcStep{id: "a", after: []string{"a"}}},
+			want:  "dependency cycle: a -> a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewGraph(tt.steps...)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("NewGraph() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGraphOrder(t *testing.T) {
+	g, err := NewGraph(
+		&funcStep{id: "login", dependsOn: []string{"install"}},
+		&funcStep{id: "report", after: []string{"login", "up"}},
+		&funcStep{id: "install"},
+		&funcStep{id: "up", after: []string{"install"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Both kinds of reference order a step after the steps it names; independent steps keep registration order
+	want := []string{"install", "login", "up", "report"}
+	if got := ids(g.Steps()); !slices.Equal(got, want) {
+		t.Errorf("Steps() = %q, want %q", got, want)
+	}
+}
+
+func TestGraphRunDependsOnAndAfter(t *testing.T) {
+	g, err := NewGraph(
+		&funcStep{id: "install", title: "Install packages", enabled: true, run: func() error { return errors.New("no network") }},
+		&funcStep{id: "login", title: "Log in", enabled: true, dependsOn: []string{"install"}},
+		&funcStep{id: "configure", title: "Configure", enabled: true, dependsOn: []string{"login"}},
+		&funcStep{id: "report", title: "Report", enabled: true, after: []string{"install"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r recorder
+	results := g.Run(&r)
+
+	// A failed dependency skips its dependents transitively; a step that is only ordered after it still runs
+	want := map[string]Status{
+		"install":   StatusFailed,
+		"login":     StatusMissingPrereq,
+		"configure": StatusMissingPrereq,
+		"report":    StatusSucceeded,
+	}
+	if got := statuses(results); !maps.Equal(got, want) {
+		t.Errorf("statuses = %v, want %v", got, want)
+	}
+	if err := results[1].Err; err == nil || err.Error() != "requires install packages, which did not complete" {
+		t.Errorf("login error = %v", err)
+	}
+	if err := results[2].Err; err == nil || err.Error() != "requires log in, which did not complete" {
+		t.Errorf("configure error = %v", err)
+	}
+	if len(r.finished) != 4 {
+		t.Errorf("reported %d results, want 4", len(r.finished))
+	}
+}
+
+func TestGraphRunMetDependencies(t *testing.T) {
+	var ran []string
+	step := func(id string, s funcStep) *funcStep {
+		s.id, s.title = id, id
+		s.run = func() error {
+			ran = append(ran, id)
+			return nil
+		}
+		return &s
+	}
+
+	g, err := NewGraph(
+		step("unselected", funcStep{}),
+		step("done", funcStep{enabled: true, satisfied: func() bool { return true }}),
+		step("blocked", funcStep{enabled: true, check: func() error { return errors.New("gh is not installed") }}),
+		step("a", funcStep{enabled: true, dependsOn: []string{"unselected", "done"}}),
+		step("b", funcStep{enabled: true, dependsOn: []string{"blocked"}}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r recorder
+	results := g.Run(&r)
+
+	// Dependencies that were not selected or already done count as met; one skipped for a missing prerequisite does not
+	want := map[string]Status{
+		"unselected": StatusNotSelected,
+		"done":       StatusAlreadyDone,
+		"blocked":    StatusMissingPrereq,
+		"a":          StatusSucceeded,
+		"b":          StatusMissingPrereq,
+	}
+	if got := statuses(results); !maps.Equal(got, want) {
+		t.Errorf("statuses = %v, want %v", got, want)
+	}
+	if !slices.Equal(ran, []string{"a"}) {
+		t.Errorf("ran %q, want only a", ran)
+	}
+	// Unselected steps are neither started nor reported
+	if want := []string{"done", "blocked", "a", "b"}; !slices.Equal(r.started, want) {
+		t.Errorf("started %q, want %q", r.started, want)
+	}
+}
+
+func TestGraphRunVerifyFailure(t *testing.T) {
+	g, err := NewGraph(&funcStep{id: "a", enabled: true, verify: func() error { return errors.New("gh is not on PATH") }})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := g.Run(&recorder{})
+	if results[0].Status != StatusFailed || results[0].Err == nil || results[0].Err.Error() != "postcondition failed: gh is not on PATH" {
+		t.Errorf("result = %+v, want a failed postcondition", results[0])
+	}
+}
+
+func TestGraphRunCriticalFailure(t *testing.T) {
+	var ran []string
+	run := func(id string, err error) func() error {
+		return func() error {
+			ran = append(ran, id)
+			return err
+		}
+	}
+
+	g, err := NewGraph(
+		&funcStep{id: "optional", title: "Optional", enabled: true, run: run("optional", errors.New("boom"))},
+		&funcStep{id: "manager", title: "Package manager", enabled: true, critical: true, run: run("manager", errors.New("no brew"))},
+		&funcStep{id: "packages", title: "Packages", enabled: true, run: run("packages", nil)},
+		&funcStep{id: "unselected", title: "Unselected"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r recorder
+	results := g.Run(&r)
+
+	// Only a critical failure stops the run; everything after it is not run, selected or not
+	want := map[string]Status{
+		"optional":   StatusFailed,
+		"manager":    StatusFailed,
+		"packages":   StatusNotRun,
+		"unselected": StatusNotRun,
+	}
+	if got := statuses(results); !maps.Equal(got, want) {
+		t.Errorf("statuses = %v, want %v", got, want)
+	}
+	if !slices.Equal(ran, []string{"optional", "manager"}) {
+		t.Errorf("ran %q, want the steps up to the critical failure", ran)
+	}
+	if err := results[2].Err; err == nil || err.Error() != "run stopped after Package manager failed" {
+		t.Errorf("packages error = %v", err)
+	}
+	if len(r.finished) != 2 {
+		t.Errorf("reported %d results, want only the steps that ran", len(r.finished))
+	}
+}
+
+func TestGraphRunCompletedStep(t *testing.T) {
+	ran := false
+	login := &funcStep{
+		id:      "login",
+		title:   "Log in",
+		enabled: true,
+		check:   func() error { return errors.New("op is not installed") },
+		run: func() error {
+			ran = true
+			return nil
+		},
+	}
+	g, err := NewGraph(
+		completedStep{login},
+		&funcStep{id: "configure", title: "Configure", enabled: true, dependsOn: []string{"login"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := g.Run(&recorder{})
+
+	// A step completed in an earlier run is neither checked nor run again, and still satisfies its dependents
+	want := map[string]Status{"login": StatusAlreadyDone, "configure": StatusSucceeded}
+	if got := statuses(results); !maps.Equal(got, want) {
+		t.Errorf("statuses = %v, want %v", got, want)
+	}
+	if ran {
+		t.Error("completed step ran again")
+	}
+	if results[0].Title != "Log in" {
+		t.Errorf("completed step title = %q, want the wrapped step's", results[0].Title)
+	}
+}
@@ -0,0 +1,260 @@
+package executor_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/whexy/wenxuan-dev-init/pkg/installer"
+	"github.com/whexy/wenxuan-dev-init/pkg/installer/installertest"
+	"github.com/whexy/wenxuan-dev-init/pkg/logger"
+)
+
+// shimPrologue starts every shim: it logs the invocation and defines install_tools,
+// which puts the shims of the tools a package provides on PATH, like a real install would
+const shimPrologue = `#!/bin/sh
+printf '%s\n' "${0##*/} $*" >> "$SHIM_LOG"
+install_tools() {
+	for pkg in "$@"; do
+		[ -d "$SHIM_DIR/packages/$pkg" ] || continue
+		for tool in "$SHIM_DIR/packages/$pkg"/*; do
+			/bin/cp "$tool" "$SHIM_DIR/bin/${tool##*/}"
+		done
+	done
+}
+`
+
+// harness runs installer commands against shim scripts in a temp dir that replaces PATH.
+// Shims record their invocations, so tests can assert the exact commands a run produced.
+type harness struct {
+	t    *testing.T
+	dir  string
+	log  string
+	home string
+	// files holds the files the installer reads and writes itself, such as /etc repository files
+	files *installertest.FileSystem
+
+	mu   sync.Mutex
+	urls []string
+}
+
+// newHarness puts an empty shim directory on PATH, points HOME, the state directory and the installer's
+// filesystem at temp dirs and makes downloads return a stub. Everything is restored when the test ends.
+func newHarness(t *testing.T) *harness {
+	t.Helper()
+	h := &harness{t: t, dir: t.TempDir(), home: t.TempDir(), files: installertest.NewFileSystem(t.TempDir())}
+	h.log = filepath.Join(h.dir, "calls.log")
+	for _, sub := range []string{"bin", "packages"} {
+		if err := os.Mkdir(filepath.Join(h.dir, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Setenv("PATH", filepath.Join(h.dir, "bin"))
+	t.Setenv("SHIM_DIR", h.dir)
+	t.Setenv("SHIM_LOG", h.log)
+	t.Setenv("HOME", h.home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(h.home, ".config"))
+	t.Setenv("XDG_STATE_HOME", filepath.Join(h.home, ".local", "state"))
+	t.Setenv("SHELL", "/bin/bash")
+	t.Setenv("OP_SERVICE_ACCOUNT_TOKEN", "ops_test")
+
+	prevLog := logger.Output()
+	logger.SetOutput(io.Discard)
+	t.Cleanup(installer.SetOutput(io.Discard))
+	installer.SetRunner(h)
+	installer.SetFileSystem(h.files)
+	installer.SetHTTPClient(&http.Client{Transport: h})
+	installer.SetNonInteractive(true)
+	installer.SetUseServiceAccount(true)
+	t.Cleanup(func() {
+		logger.SetOutput(prevLog)
+		installer.SetRunner(nil)
+		installer.SetFileSystem(nil)
+		installer.SetHTTPClient(nil)
+		installer.SetNonInteractive(false)
+		installer.SetUseServiceAccount(false)
+		installer.SetPreferredPackageManager("")
+	})
+
+	// sudo passes through unlogged, so the calls are the same whether or not the tests run as root
+	h.write(filepath.Join(h.dir, "bin", "sudo"), "#!/bin/sh\nexec \"$@\"\n")
+	return h
+}
+
+// Run runs commands on this machine, except that a program named by a path in the installer's filesystem runs from there,
+// like the chezmoi that the official installer puts in /usr/local/bin
+func (h *harness) Run(ctx context.Context, cmd installer.Command) error {
+	if name := cmd.Args[0]; filepath.IsAbs(name) {
+		if _, err := h.files.Stat(name); err == nil {
+			cmd.Args = append([]string{h.files.Path(name)}, cmd.Args[1:]...)
+		}
+	}
+	return installer.ExecRunner{}.Run(ctx, cmd)
+}
+
+// RoundTrip answers every download with a stub and records its URL
+func (h *harness) RoundTrip(req *http.Request) (*http.Response, error) {
+	h.mu.Lock()
+	h.urls = append(h.urls, req.URL.String())
+	h.mu.Unlock()
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader("# " + req.URL.String() + "\n")),
+		Request:    req,
+	}, nil
+}
+
+// shim puts a command on PATH that logs its invocation and then runs body; an empty body exits 0
+func (h *harness) shim(name, body string) {
+	h.write(filepath.Join(h.dir, "bin", name), shimPrologue+body+"\n")
+}
+
+// provide makes installing pkg put a shim for tool on PATH; installers call install_tools for that
+func (h *harness) provide(pkg, tool, body string) {
+	dir := filepath.Join(h.dir, "packages", pkg)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		h.t.Fatal(err)
+	}
+	h.write(filepath.Join(dir, tool), shimPrologue+body+"\n")
+}
+
+func (h *harness) write(path, content string) {
+	h.t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0755); err != nil {
+		h.t.Fatal(err)
+	}
+}
+
+// writeFile creates a file in the installer's filesystem
+func (h *harness) writeFile(name, content string) {
+	h.t.Helper()
+	if err := h.files.Write(name, content); err != nil {
+		h.t.Fatal(err)
+	}
+}
+
+// calls returns the logged invocations, one "name args" line each
+func (h *harness) calls() []string {
+	h.t.Helper()
+	data, err := os.ReadFile(h.log)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+// downloads returns the URLs fetched so far
+func (h *harness) downloads() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.urls...)
+}
+
+// managerShims are the package manager commands; the ones that install packages put the packages' tools on PATH
+var managerShims = map[string]string{
+	"apt":    "apt-get",
+	"dnf":    "dnf",
+	"yum":    "yum",
+	"zypper": "zypper",
+	"pacman": "pacman",
+	"brew":   "brew",
+	"devbox": "devbox",
+}
+
+// osReleases are the /etc/os-release files of a distribution that uses each package manager
+var osReleases = map[string]string{
+	"apt":    "ID=ubuntu\nVERSION_ID=\"24.04\"\nVERSION_CODENAME=noble\n",
+	"dnf":    "ID=fedora\nVERSION_ID=40\n",
+	"yum":    "ID=\"rocky\"\nVERSION_ID=\"8.9\"\n",
+	"zypper": "ID=\"opensuse-tumbleweed\"\nVERSION_ID=\"20240601\"\n",
+	"pacman": "ID=arch\n",
+}
+
+// freshMachine sets up shims for a machine that has manager and the usual base tools, but none of the tools setup installs
+func (h *harness) freshMachine(manager string) {
+	h.t.Helper()
+	if release, ok := osReleases[manager]; ok {
+		h.writeFile("/etc/os-release", release)
+	}
+	for _, name := range []string{"curl", "gpg", "tee", "dd", "rpm", "dpkg", "dpkg-query", "systemctl"} {
+		h.shim(name, "")
+	}
+	switch manager {
+	case "apt":
+		h.shim("apt-get", `if [ "$1" = install ]; then install_tools "$@"; fi`)
+	case "":
+	default:
+		h.shim(managerShims[manager], `install_tools "$@"`)
+	}
+	// Official installer scripts run with sh; chezmoi is the only tool installed that way
+	h.shim("sh", "install_tools chezmoi")
+
+	h.provide("git", "git", "")
+	h.provide("gh", "gh", ghShim)
+	h.provide("github-cli", "gh", ghShim)
+	h.provide("1password-cli", "op", opShim)
+	h.provide("_1password-cli", "op", opShim)
+	h.provide("chezmoi", "chezmoi", "")
+	h.provide("tailscale", "tailscale", tailscaleShim)
+}
+
+// configuredMachine adds the tools setup installs to a fresh machine, already signed in and connected
+func (h *harness) configuredMachine(manager string) {
+	h.t.Helper()
+	h.freshMachine(manager)
+	h.shim("git", "")
+	h.shim("gh", "")
+	h.shim("op", `if [ "$1" = read ]; then printf 'secret-for-%s\n' "$2"; fi`)
+	h.shim("chezmoi", "")
+	h.shim("tailscale", `printf '{"BackendState":"Running"}\n'`)
+}
+
+// Tool shims that behave like a fresh machine: nobody is signed in and tailscaled needs a login
+const (
+	opShim = `case "$1" in
+whoami) exit 1 ;;
+read) printf 'secret-for-%s\n' "$2" ;;
+esac`
+	ghShim = `case "$1 $2" in
+"auth status") exit 1 ;;
+"auth login") while read -r line; do :; done ;;
+esac`
+	tailscaleShim = `case "$1" in
+status) printf '{"BackendState":"NeedsLogin"}\n'; exit 1 ;;
+esac`
+)
+
+// assertCalls compares the logged invocations with want, line by line
+func assertCalls(t *testing.T, got, want []string) {
+	t.Helper()
+	if strings.Join(got, "\n") == strings.Join(want, "\n") {
+		return
+	}
+	var b bytes.Buffer
+	n := max(len(got), len(want))
+	for i := 0; i < n; i++ {
+		var g, w string
+		if i < len(got) {
+			g = got[i]
+		}
+		if i < len(want) {
+			w = want[i]
+		}
+		mark := " "
+		if g != w {
+			mark = "!"
+		}
+		b.WriteString(mark + " got:  " + g + "\n  want: " + w + "\n")
+	}
+	t.Errorf("command sequence differs:\n%s", b.String())
+}
@@ -0,0 +1,70 @@
+package executor_test
+
+import (
+	"bytes"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/whexy/wenxuan-dev-init/pkg/executor"
+	"github.com/whexy/wenxuan-dev-init/pkg/logger"
+	"github.com/whexy/wenxuan-dev-init/pkg/profile"
+)
+
+func TestPlanRestoresLogOutput(t *testing.T) {
+	h := newHarness(t)
+	h.freshMachine("apt")
+
+	// plan --json moves the log to stderr before planning
+	var log bytes.Buffer
+	logger.SetOutput(&log)
+
+	if _, err := executor.New(options("install_git"), profile.Default()).Plan(); err != nil {
+		t.Fatal(err)
+	}
+	if logger.Output() != &log {
+		t.Fatalf("Plan() left the log output at %v", logger.Output())
+	}
+	if log.Len() != 0 {
+		t.Errorf("Plan() logged %q, want nothing", log.String())
+	}
+	logger.Println("after")
+	if log.String() != "after\n" {
+		t.Errorf("log after Plan() = %q, want the line printed afterwards", log.String())
+	}
+}
+
+func TestPlanReportsPlanningErrors(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("on macOS a missing package manager means installing Homebrew")
+	}
+	h := newHarness(t)
+	// No package manager on PATH, so the critical package manager step cannot be planned
+	h.freshMachine("")
+
+	_, err := executor.New(options("install_git", "install_gh"), profile.Default()).Plan()
+	if err == nil {
+		t.Fatal("Plan() = nil, want the package manager detection error")
+	}
+	if !strings.Contains(err.Error(), "no supported package manager found") {
+		t.Errorf("Plan() error = %v, want the package manager detection error", err)
+	}
+	if errors.Is(err, executor.ErrPartialFailure) || errors.Is(err, executor.ErrTotalFailure) {
+		t.Errorf("Plan() error = %v, want a planning error, not a step outcome", err)
+	}
+}
+
+func TestPlanIgnoresStepFailures(t *testing.T) {
+	h := newHarness(t)
+	h.freshMachine("apt")
+
+	// Setting up GitHub without gh or op installed would be skipped; that is a plan note, not an error
+	plan, err := executor.New(options("setup_github"), profile.Default()).Plan()
+	if err != nil {
+		t.Fatalf("Plan() error = %v, want nil", err)
+	}
+	if plan.PackageManager != "apt" {
+		t.Errorf("plan.PackageManager = %q, want apt", plan.PackageManager)
+	}
+}
@@ -0,0 +1,65 @@
+package executor
+
+// funcStep is a Step assembled from plain functions; nil functions mean "nothing to do"
+type funcStep struct {
+	id        string
+	title     string
+	icon      string
+	dependsOn []string
+	after     []string
+	enabled   bool
+	critical  bool
+	hint      string
+	// repeat makes the step run again when resuming, because later steps need what it sets up in this process
+	repeat bool
+
+	check     func() error
+	satisfied func() bool
+	run       func() error
+	verify    func() error
+}
+
+func (s *funcStep) ID() string          { return s.id }
+func (s *funcStep) Title() string       { return s.title }
+func (s *funcStep) Icon() string        { return s.icon }
+func (s *funcStep) DependsOn() []string { return s.dependsOn }
+func (s *funcStep) After() []string     { return s.after }
+func (s *funcStep) Enabled() bool       { return s.enabled }
+func (s *funcStep) Critical() bool      { return s.critical }
+func (s *funcStep) Hint() string        { return s.hint }
+
+func (s *funcStep) Check() error {
+	if s.check == nil {
+		return nil
+	}
+	return s.check()
+}
+
+func (s *funcStep) Satisfied() bool {
+	if s.satisfied == nil {
+		return false
+	}
+	return s.satisfied()
+}
+
+func (s *funcStep) Run() error {
+	if s.run == nil {
+		return nil
+	}
+	return s.run()
+}
+
+func (s *funcStep) Verify() error {
+	if s.verify == nil {
+		return nil
+	}
+	return s.verify()
+}
+
+// completedStep stands in for a step that completed in an earlier run of a resumed setup
+type completedStep struct {
+	Step
+}
+
+func (completedStep) Check() error    { return nil }
+func (completedStep) Satisfied() bool { return true }
@@ -0,0 +1,114 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/whexy/wenxuan-dev-init/pkg/logger"
+)
+
+var (
+	// ErrPartialFailure is returned when some selected steps failed and others succeeded
+	ErrPartialFailure = errors.New("setup finished with failures")
+	// ErrTotalFailure is returned when none of the selected steps succeeded
+	ErrTotalFailure = errors.New("setup failed")
+)
+
+// criticalFailure is returned when a critical step fails. The run cannot go on, so it is a total failure.
+type criticalFailure struct {
+	title string
+	err   error
+}
+
+func (e *criticalFailure) Error() string {
+	return fmt.Sprintf("%s failed: %v", lowerFirst(e.title), e.err)
+}
+
+func (e *criticalFailure) Unwrap() []error {
+	return []error{ErrTotalFailure, e.err}
+}
+
+// statusLabels are the summary labels for each status
+var statusLabels = map[Status]string{
+	StatusSucceeded:     "✅ succeeded",
+	StatusAlreadyDone:   "⏭️  already done",
+	StatusMissingPrereq: "⚠️  skipped",
+	StatusFailed:        "❌ failed",
+	StatusNotRun:        "⏹️  not run",
+}
+
+// Results returns the outcome of every step from the last Execute call
+func (e *Executor) Results() []Result {
+	return e.results
+}
+
+// outcome classifies a finished run.
+// Critical steps are prerequisites rather than goals, so they never count as the run succeeding.
+func outcome(results []Result, critical map[string]bool) error {
+	var achieved, broken int
+	for _, res := range results {
+		switch res.Status {
+		case StatusSucceeded, StatusAlreadyDone:
+			if !critical[res.ID] {
+				achieved++
+			}
+		case StatusMissingPrereq, StatusFailed, StatusNotRun:
+			broken++
+		}
+	}
+
+	switch {
+	case broken == 0:
+		return nil
+	case achieved == 0:
+		return fmt.Errorf("%w: %d of the selected steps did not complete", ErrTotalFailure, broken)
+	}
+	return fmt.Errorf("%w: %d of the selected steps did not complete", ErrPartialFailure, broken)
+}
+
+// PrintSummary prints one line per selected step with the reason and a remediation hint for problems
+func PrintSummary(results []Result) {
+	width := 0
+	for _, res := range results {
+		if len(res.Title) > width {
+			width = len(res.Title)
+		}
+	}
+
+	logger.Println("")
+	logger.Step("📊", "Summary")
+	for _, res := range results {
+		if res.Status == StatusNotSelected {
+			continue
+		}
+
+		line := fmt.Sprintf("  %-*s  %s", width, res.Title, statusLabels[res.Status])
+		var output []string
+		if res.Err != nil {
+			msg := strings.Split(res.Err.Error(), "\n")
+			line += ": " + msg[0]
+			output = msg[1:]
+		}
+		logger.Println(line)
+
+		// Failed commands carry the end of their output
+		for _, l := range output {
+			logger.Println(fmt.Sprintf("  %-*s  │ %s", width, "", l))
+		}
+
+		switch res.Status {
+		case StatusMissingPrereq, StatusFailed, StatusNotRun:
+			if res.Hint != "" {
+				logger.Println(fmt.Sprintf("  %-*s  → %s", width, "", res.Hint))
+			}
+		}
+	}
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
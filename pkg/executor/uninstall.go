@@ -0,0 +1,146 @@
+package executor
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/whexy/wenxuan-dev-init/pkg/installer"
+	"github.com/whexy/wenxuan-dev-init/pkg/logger"
+	"github.com/whexy/wenxuan-dev-init/pkg/state"
+)
+
+// DescribeUninstall lists what Uninstall would undo, one line per change
+func DescribeUninstall(m *state.Manifest, removePackages bool) []string {
+	var lines []string
+	for _, login := range m.Logins {
+		lines = append(lines, "Sign out of "+loginLabel(login))
+	}
+	for _, f := range m.Files {
+		switch {
+		case keepReason(f) != "":
+		case f.Append != "":
+			lines = append(lines, "Remove the lines setup added to "+f.Path)
+		default:
+			lines = append(lines, "Delete "+f.Path)
+		}
+	}
+	if removePackages {
+		packages := m.PackagesByManager()
+		for _, manager := range sortedKeys(packages) {
+			lines = append(lines, fmt.Sprintf("Remove %v with %s", packages[manager], manager))
+		}
+	}
+	return lines
+}
+
+// Uninstall undoes what setup recorded in m: it signs out of the accounts setup signed in to, removes what it added
+// to shell rc files, deletes the files it created and, with removePackages, removes the packages it installed.
+// Files that changed since setup wrote them and files setup only modified are left in place.
+// Each change that is undone is dropped from the manifest, so a failed uninstall can be run again.
+func Uninstall(m *state.Manifest, removePackages bool) error {
+	var undone, failed int
+	undo := func(what string, fn func() error, forget func()) {
+		if err := fn(); err != nil {
+			logger.Error(fmt.Sprintf("%s failed: %s", what, firstLine(err.Error())))
+			failed++
+			return
+		}
+		forget()
+		if err := m.Save(); err != nil {
+			logger.Warning(err.Error())
+		}
+		logger.Success(what)
+		undone++
+	}
+
+	// Sign out first, while the tools are still installed
+	for _, login := range slices.Clone(m.Logins) {
+		undo("Sign out of "+loginLabel(login), func() error {
+			return installer.Logout(login)
+		}, func() { m.RemoveLogin(login) })
+	}
+
+	for _, f := range slices.Clone(m.Files) {
+		switch reason := keepReason(f); {
+		case reason != "":
+			logger.Info(fmt.Sprintf("Keeping %s: %s", f.Path, reason))
+			m.RemoveFile(f.Path)
+			if err := m.Save(); err != nil {
+				logger.Warning(err.Error())
+			}
+		case f.Append != "":
+			undo("Clean up "+f.Path, func() error {
+				return installer.RemoveAppend(f.Path, f.Append)
+			}, func() { m.RemoveFile(f.Path) })
+		default:
+			undo("Delete "+f.Path, func() error {
+				return installer.RemoveFile(f.Path)
+			}, func() { m.RemoveFile(f.Path) })
+		}
+	}
+
+	if removePackages {
+		packages := m.PackagesByManager()
+		for _, manager := range sortedKeys(packages) {
+			names := packages[manager]
+			logger.Step("🗑️", fmt.Sprintf("Removing %v with %s...", names, manager))
+			undo(fmt.Sprintf("Remove %v", names), func() error {
+				pkgMgr, err := installer.PackageManagerByName(manager)
+				if err != nil {
+					return err
+				}
+				return pkgMgr.Remove(names...)
+			}, func() {
+				for _, name := range names {
+					m.RemovePackage(manager, name)
+				}
+			})
+		}
+	} else if len(m.Packages) > 0 {
+		logger.Info("Installed packages are kept; pass --packages to remove them too")
+	}
+
+	switch {
+	case failed == 0:
+		return nil
+	case undone == 0:
+		return fmt.Errorf("%w: %d changes could not be undone", ErrTotalFailure, failed)
+	}
+	return fmt.Errorf("%w: %d changes could not be undone", ErrPartialFailure, failed)
+}
+
+// keepReason says why uninstall leaves a recorded file in place, or returns "" when it undoes the change
+func keepReason(f state.File) string {
+	if f.Append != "" {
+		return ""
+	}
+	if !f.Created {
+		return "it existed before setup changed it"
+	}
+	if current := installer.FileHash(f.Path); f.SHA256 != "" && current != "" && current != f.SHA256 {
+		return "it changed since setup wrote it"
+	}
+	return ""
+}
+
+func sortedKeys(m map[string][]string) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func loginLabel(login state.Login) string {
+	switch login.Service {
+	case state.LoginGitHub:
+		return "GitHub (gh) on " + login.Account
+	case state.LoginTailscale:
+		return "Tailscale"
+	case state.Login1Password:
+		return "1Password account " + login.Account
+	}
+	return login.Service
+}
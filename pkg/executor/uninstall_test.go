@@ -0,0 +1,123 @@
+package executor_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/whexy/wenxuan-dev-init/pkg/executor"
+	"github.com/whexy/wenxuan-dev-init/pkg/installer"
+	"github.com/whexy/wenxuan-dev-init/pkg/profile"
+	"github.com/whexy/wenxuan-dev-init/pkg/state"
+)
+
+// manifest loads the manifest from the harness's state directory
+func (h *harness) manifest() *state.Manifest {
+	h.t.Helper()
+	m, err := state.LoadManifest()
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	return m
+}
+
+// readFile returns a file from the installer's filesystem, or "" when it does not exist
+func (h *harness) readFile(name string) string {
+	h.t.Helper()
+	data, err := os.ReadFile(h.files.Path(name))
+	if os.IsNotExist(err) {
+		return ""
+	}
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestUninstallKeepsChangedFiles(t *testing.T) {
+	h := newHarness(t)
+	h.shim("rm", "")
+	const (
+		unchanged = "/etc/apt/sources.list.d/github-cli.list"
+		changed   = "/etc/apt/sources.list.d/1password.list"
+	)
+	m := h.manifest()
+	for _, path := range []string{unchanged, changed} {
+		h.writeFile(path, "deb https://example.com stable main\n")
+		m.AddFile(state.File{Path: path, Created: true, SHA256: installer.FileHash(path), Time: time.Now()})
+	}
+	h.writeFile(changed, "deb https://example.com stable main contrib\n")
+
+	if err := executor.Uninstall(m, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the file that still has the content setup wrote is deleted
+	assertCalls(t, h.calls(), []string{"rm -f " + unchanged})
+	if h.readFile(changed) == "" {
+		t.Errorf("%s was removed", changed)
+	}
+	if !m.IsEmpty() {
+		t.Errorf("manifest still records %+v", m.Files)
+	}
+}
+
+func TestUninstallRemovesOnlyAppendedText(t *testing.T) {
+	h := newHarness(t)
+	bashrc := filepath.Join(h.home, ".bashrc")
+	gitconfig := filepath.Join(h.home, ".gitconfig")
+	const shellenv = "eval \"$(devbox global shellenv)\"\n"
+	h.writeFile(bashrc, "alias ll='ls -l'\n"+shellenv+"export EDITOR=vim\n")
+	h.writeFile(gitconfig, "[user]\n\tname = wx\n[credential]\n\thelper = !gh auth git-credential\n")
+
+	m := h.manifest()
+	m.AddFile(state.File{Path: bashrc, Append: shellenv, Time: time.Now()})
+	m.AddFile(state.File{Path: gitconfig, SHA256: installer.FileHash(gitconfig), Time: time.Now()})
+
+	if err := executor.Uninstall(m, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Files that existed before setup keep everything but the lines setup appended
+	if got, want := h.readFile(bashrc), "alias ll='ls -l'\nexport EDITOR=vim\n"; got != want {
+		t.Errorf(".bashrc = %q, want %q", got, want)
+	}
+	if got := h.readFile(gitconfig); got != "[user]\n\tname = wx\n[credential]\n\thelper = !gh auth git-credential\n" {
+		t.Errorf(".gitconfig = %q, want it unchanged", got)
+	}
+	assertCalls(t, h.calls(), nil)
+}
+
+func TestUninstallKeepsPreinstalledPackages(t *testing.T) {
+	h := newHarness(t)
+	h.freshMachine("apt")
+	// git was installed before setup ran; dpkg knows the packages whose tools are on PATH
+	h.shim("git", "")
+	h.shim("dpkg-query", `case "$2" in
+'-f=${Status}') [ -e "$SHIM_DIR/bin/$3" ] && printf 'install ok installed' ;;
+'-f=${Version}') printf '1.0' ;;
+esac`)
+	if err := installer.SetPreferredPackageManager("apt"); err != nil {
+		t.Fatal(err)
+	}
+	m := h.manifest()
+	installer.SetManifest(m)
+	t.Cleanup(func() { installer.SetManifest(nil) })
+
+	e := executor.New(options("install_git", "install_gh"), profile.Default())
+	e.SetNonInteractive(true)
+	if err := e.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.PackagesByManager()["apt"]; !slices.Equal(got, []string{"gh"}) {
+		t.Fatalf("manifest records %q, want only the package setup installed", got)
+	}
+
+	setup := len(h.calls())
+	if err := executor.Uninstall(m, true); err != nil {
+		t.Fatal(err)
+	}
+	assertCalls(t, h.calls()[setup:], []string{"apt-get remove -y gh"})
+}
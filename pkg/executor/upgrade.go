@@ -0,0 +1,117 @@
+package executor
+
+import (
+	"fmt"
+
+	"github.com/whexy/wenxuan-dev-init/pkg/installer"
+	"github.com/whexy/wenxuan-dev-init/pkg/logger"
+)
+
+// ManagedTools are the tools setup installs, by catalog name
+var ManagedTools = []string{"git", "gh", "1password-cli", "chezmoi", "tailscale"}
+
+// UpgradeResult is the outcome of upgrading one tool
+type UpgradeResult struct {
+	Tool string
+	// Manager is the package manager that owns the tool, or "" when it is not installed
+	Manager string
+	Before  string
+	After   string
+	Err     error
+}
+
+// Upgrade upgrades each installed tool with the package manager that owns it, one batch per manager
+func Upgrade(tools []string) ([]UpgradeResult, error) {
+	results := make([]UpgradeResult, len(tools))
+	owners := make(map[string]installer.PackageManager)
+	batches := make(map[string][]int)
+	var order []string
+
+	for i, tool := range tools {
+		results[i].Tool = tool
+		owner := installer.FindOwner(tool)
+		if owner == nil {
+			continue
+		}
+
+		results[i].Manager = owner.Name()
+		results[i].Before, _ = owner.Version(tool)
+		if _, ok := owners[owner.Name()]; !ok {
+			owners[owner.Name()] = owner
+			order = append(order, owner.Name())
+		}
+		batches[owner.Name()] = append(batches[owner.Name()], i)
+	}
+
+	for _, name := range order {
+		owner := owners[name]
+		var batch []string
+		for _, i := range batches[name] {
+			batch = append(batch, results[i].Tool)
+		}
+
+		logger.Step("⬆️", fmt.Sprintf("Upgrading %v with %s...", batch, name))
+		err := owner.Upgrade(batch...)
+		for _, i := range batches[name] {
+			results[i].Err = err
+			results[i].After, _ = owner.Version(results[i].Tool)
+		}
+	}
+
+	return results, upgradeOutcome(results)
+}
+
+// upgradeOutcome classifies an upgrade like a setup run
+func upgradeOutcome(results []UpgradeResult) error {
+	var upgraded, failed int
+	for _, res := range results {
+		switch {
+		case res.Err != nil:
+			failed++
+		case res.Manager != "":
+			upgraded++
+		}
+	}
+
+	switch {
+	case failed == 0:
+		return nil
+	case upgraded == 0:
+		return fmt.Errorf("%w: %d tools could not be upgraded", ErrTotalFailure, failed)
+	}
+	return fmt.Errorf("%w: %d tools could not be upgraded", ErrPartialFailure, failed)
+}
+
+// PrintUpgradeSummary prints one line per tool with its package manager and versions before and after
+func PrintUpgradeSummary(results []UpgradeResult) {
+	width := 0
+	for _, res := range results {
+		if len(res.Tool) > width {
+			width = len(res.Tool)
+		}
+	}
+
+	logger.Println("")
+	logger.Step("📊", "Summary")
+	for _, res := range results {
+		var status string
+		switch {
+		case res.Manager == "":
+			status = "⏭️  not installed"
+		case res.Err != nil:
+			status = fmt.Sprintf("❌ failed (%s): %s", res.Manager, firstLine(res.Err.Error()))
+		case res.Before == res.After:
+			status = fmt.Sprintf("✅ up to date (%s, %s)", res.Manager, versionLabel(res.After))
+		default:
+			status = fmt.Sprintf("✅ upgraded (%s, %s → %s)", res.Manager, versionLabel(res.Before), versionLabel(res.After))
+		}
+		logger.Println(fmt.Sprintf("  %-*s  %s", width, res.Tool, status))
+	}
+}
+
+func versionLabel(version string) string {
+	if version == "" {
+		return "unknown version"
+	}
+	return version
+}
@@ -0,0 +1,278 @@
+package executor_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/whexy/wenxuan-dev-init/pkg/executor"
+	"github.com/whexy/wenxuan-dev-init/pkg/logger"
+)
+
+// upgradePackages is shell for the upgrade shims: `upgrade_packages db pkg...` moves the packages installed in the
+// package database db to their latest version
+const upgradePackages = `upgrade_packages() {
+	db="$SHIM_DIR/versions/$1"
+	shift
+	for pkg in "$@"; do
+		[ -e "$db/$pkg.latest" ] && /bin/cp "$db/$pkg.latest" "$db/$pkg"
+	done
+	return 0
+}
+`
+
+// Query shims that answer from the package databases kept by installed
+const (
+	dpkgQueryShim = `f="$SHIM_DIR/versions/apt/$3"
+[ -e "$f" ] || exit 1
+case "$2" in
+'-f=${Status}') printf 'install ok installed' ;;
+*) /bin/cat "$f" ;;
+esac`
+	rpmShim = `if [ "$2" = --qf ]; then
+	f="$SHIM_DIR/versions/rpm/$4"
+	[ -e "$f" ] && /bin/cat "$f"
+else
+	[ -e "$SHIM_DIR/versions/rpm/$2" ]
+fi`
+	devboxShim = upgradePackages + `case "$1 $2" in
+"global list")
+	for f in "$SHIM_DIR"/versions/devbox/*; do
+		case "$f" in *.latest|*'*') continue ;; esac
+		printf '* %s@latest - %s\n' "${f##*/}" "$(/bin/cat "$f")"
+	done ;;
+"global update") shift 2; upgrade_packages devbox "$@" ;;
+esac`
+	// chezmoiBinary is the chezmoi that get.chezmoi.io installs in /usr/local/bin
+	chezmoiBinary = `printf 'chezmoi version v%s, built at 2024-11-01\n' "$(/bin/cat "$SHIM_DIR/versions/vendor/chezmoi")"`
+)
+
+// installed records pkg at version in the package database db (apt, rpm, devbox or vendor); upgrading it installs latest
+func (h *harness) installed(db, pkg, version, latest string) {
+	h.t.Helper()
+	dir := filepath.Join(h.dir, "versions", db)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		h.t.Fatal(err)
+	}
+	h.write(filepath.Join(dir, pkg), version)
+	h.write(filepath.Join(dir, pkg+".latest"), latest)
+}
+
+// upgradeMachine sets up the shims of a machine where manager and the rpm, dpkg and devbox databases answer from installed
+func (h *harness) upgradeMachine(manager string) {
+	h.t.Helper()
+	h.freshMachine(manager)
+	h.shim("dpkg-query", dpkgQueryShim)
+	h.shim("rpm", rpmShim)
+	switch manager {
+	case "apt":
+		h.shim("apt-get", upgradePackages+`if [ "$1 $3" = "install --only-upgrade" ]; then shift 3; upgrade_packages apt "$@"; fi`)
+	case "dnf":
+		h.shim("dnf", upgradePackages+`if [ "$1" = upgrade ]; then shift 2; upgrade_packages rpm "$@"; fi`)
+	case "yum":
+		h.shim("yum", upgradePackages+`if [ "$1" = update ]; then shift 2; upgrade_packages rpm "$@"; fi`)
+	}
+	// Running get.chezmoi.io again installs the latest chezmoi
+	h.shim("sh", upgradePackages+"upgrade_packages vendor chezmoi")
+}
+
+// curlChezmoi puts chezmoi version in /usr/local/bin, the way get.chezmoi.io installs it
+func (h *harness) curlChezmoi(version, latest string) {
+	h.t.Helper()
+	h.installed("vendor", "chezmoi", version, latest)
+	path := h.files.Path("/usr/local/bin/chezmoi")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		h.t.Fatal(err)
+	}
+	h.write(path, shimPrologue+chezmoiBinary+"\n")
+}
+
+// changes drops the read-only queries from calls, leaving the commands that change the machine
+func changes(calls []string) []string {
+	return slices.DeleteFunc(calls, func(call string) bool {
+		for _, query := range []string{"dpkg-query ", "rpm -q", "devbox global list", "chezmoi --version"} {
+			if strings.HasPrefix(call, query) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func TestUpgrade(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("on macOS Homebrew owns every tool")
+	}
+
+	tests := []struct {
+		name    string
+		manager string
+		machine func(h *harness)
+		want    []executor.UpgradeResult
+		// calls are the commands run, without the queries for installed versions
+		calls     []string
+		downloads []string
+	}{
+		{
+			name:    "apt",
+			manager: "apt",
+			machine: func(h *harness) {
+				h.installed("apt", "git", "1:2.43.0-1ubuntu7", "1:2.43.0-1ubuntu7.1")
+				h.installed("apt", "gh", "2.45.0-1", "2.45.0-1")
+				h.curlChezmoi("2.52.0", "2.53.0")
+			},
+			// apt installs chezmoi with get.chezmoi.io, so it owns the copy in /usr/local/bin and runs the script again
+			want: []executor.UpgradeResult{
+				{Tool: "git", Manager: "apt", Before: "1:2.43.0-1ubuntu7", After: "1:2.43.0-1ubuntu7.1"},
+				{Tool: "gh", Manager: "apt", Before: "2.45.0-1", After: "2.45.0-1"},
+				{Tool: "1password-cli"},
+				{Tool: "chezmoi", Manager: "apt", Before: "2.52.0", After: "2.53.0"},
+				{Tool: "tailscale"},
+			},
+			calls: []string{
+				"apt-get update",
+				"apt-get install -y --only-upgrade git gh",
+				"sh -s -- -b /usr/local/bin",
+			},
+			downloads: []string{"https://get.chezmoi.io"},
+		},
+		{
+			name:    "dnf",
+			manager: "dnf",
+			machine: func(h *harness) {
+				h.installed("rpm", "git", "2.47.0-1.fc41", "2.47.1-1.fc41")
+				h.installed("rpm", "tailscale", "1.76.1", "1.78.1")
+				h.curlChezmoi("2.52.0", "2.52.0")
+			},
+			// dnf would install chezmoi as a package, so the copy from get.chezmoi.io belongs to the vendor installer
+			want: []executor.UpgradeResult{
+				{Tool: "git", Manager: "dnf", Before: "2.47.0-1.fc41", After: "2.47.1-1.fc41"},
+				{Tool: "gh"},
+				{Tool: "1password-cli"},
+				{Tool: "chezmoi", Manager: "vendor", Before: "2.52.0", After: "2.52.0"},
+				{Tool: "tailscale", Manager: "dnf", Before: "1.76.1", After: "1.78.1"},
+			},
+			calls: []string{
+				"dnf upgrade -y git tailscale",
+				"sh -s -- -b /usr/local/bin",
+			},
+			downloads: []string{"https://get.chezmoi.io"},
+		},
+		{
+			name:    "yum",
+			manager: "yum",
+			machine: func(h *harness) {
+				h.installed("rpm", "gh", "2.62.0-1", "2.63.0-1")
+			},
+			want: []executor.UpgradeResult{
+				{Tool: "git"},
+				{Tool: "gh", Manager: "yum", Before: "2.62.0-1", After: "2.63.0-1"},
+				{Tool: "1password-cli"},
+				{Tool: "chezmoi"},
+				{Tool: "tailscale"},
+			},
+			calls: []string{"yum update -y gh"},
+		},
+		{
+			name:    "devbox globals",
+			manager: "apt",
+			machine: func(h *harness) {
+				h.shim("devbox", devboxShim)
+				h.installed("devbox", "gh", "2.61.0", "2.62.0")
+				h.installed("devbox", "_1password-cli", "2.30.0", "2.30.3")
+				h.installed("apt", "git", "1:2.43.0-1ubuntu7", "1:2.43.0-1ubuntu7")
+				// devbox wins over the system package manager for tools in its global profile
+				h.installed("apt", "gh", "2.45.0-1", "2.45.0-1")
+			},
+			want: []executor.UpgradeResult{
+				{Tool: "git", Manager: "apt", Before: "1:2.43.0-1ubuntu7", After: "1:2.43.0-1ubuntu7"},
+				{Tool: "gh", Manager: "devbox", Before: "2.61.0", After: "2.62.0"},
+				{Tool: "1password-cli", Manager: "devbox", Before: "2.30.0", After: "2.30.3"},
+				{Tool: "chezmoi"},
+				{Tool: "tailscale"},
+			},
+			calls: []string{
+				"apt-get update",
+				"apt-get install -y --only-upgrade git",
+				"devbox global update gh _1password-cli",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.upgradeMachine(tt.manager)
+			tt.machine(h)
+
+			got, err := executor.Upgrade(executor.ManagedTools)
+			if err != nil {
+				t.Fatalf("Upgrade() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Upgrade() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+			assertCalls(t, changes(h.calls()), tt.calls)
+			if !slices.Equal(h.downloads(), tt.downloads) {
+				t.Errorf("downloads = %v, want %v", h.downloads(), tt.downloads)
+			}
+		})
+	}
+}
+
+func TestUpgradeFailure(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("on macOS Homebrew owns every tool")
+	}
+	h := newHarness(t)
+	h.upgradeMachine("apt")
+	h.shim("apt-get", `if [ "$1" = install ]; then echo "E: Could not get lock /var/lib/dpkg/lock-frontend" >&2; exit 100; fi`)
+	h.installed("apt", "git", "1:2.43.0-1ubuntu7", "1:2.43.0-1ubuntu7.1")
+	h.curlChezmoi("2.52.0", "2.53.0")
+
+	// Every tool in a failed batch fails and reports the version that is still installed
+	results, err := executor.Upgrade([]string{"git", "chezmoi"})
+	if !errors.Is(err, executor.ErrTotalFailure) {
+		t.Fatalf("Upgrade() error = %v, want a total failure", err)
+	}
+	if results[0].Err == nil || results[0].After != "1:2.43.0-1ubuntu7" {
+		t.Errorf("git = %+v, want a failure at the old version", results[0])
+	}
+	// apt owns chezmoi too, and the batch stops before its installer runs
+	if results[1].Err == nil || results[1].After != "2.52.0" {
+		t.Errorf("chezmoi = %+v, want a failure at the old version", results[1])
+	}
+}
+
+func TestPrintUpgradeSummary(t *testing.T) {
+	var out bytes.Buffer
+	prev := logger.Output()
+	logger.SetOutput(&out)
+	t.Cleanup(func() { logger.SetOutput(prev) })
+
+	executor.PrintUpgradeSummary([]executor.UpgradeResult{
+		{Tool: "git", Manager: "apt", Before: "1:2.43.0-1ubuntu7", After: "1:2.43.0-1ubuntu7.1"},
+		{Tool: "gh", Manager: "devbox", Before: "2.62.0", After: "2.62.0"},
+		{Tool: "1password-cli"},
+		{Tool: "chezmoi", Manager: "vendor", Before: "", After: "2.53.0"},
+		{Tool: "tailscale", Manager: "dnf", Before: "1.76.1", After: "1.76.1", Err: errors.New("dnf exited 1\nmore")},
+	})
+
+	for _, want := range []string{
+		"git            ✅ upgraded (apt, 1:2.43.0-1ubuntu7 → 1:2.43.0-1ubuntu7.1)",
+		"gh             ✅ up to date (devbox, 2.62.0)",
+		"1password-cli  ⏭️  not installed",
+		"chezmoi        ✅ upgraded (vendor, unknown version → 2.53.0)",
+		"tailscale      ❌ failed (dnf): dnf exited 1",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("summary is missing %q:\n%s", want, out.String())
+		}
+	}
+}
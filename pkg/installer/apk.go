@@ -0,0 +1,153 @@
+package installer
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ApkManager struct{}
+
+func NewApkManager() *ApkManager {
+	return &ApkManager{}
+}
+
+func (a *ApkManager) Name() string {
+	return "apk"
+}
+
+func (a *ApkManager) IsAvailable() bool {
+	return IsCommandAvailable("apk")
+}
+
+func (a *ApkManager) Install(packages ...string) error {
+	// There is no Alpine package for 1password-cli; its static binary runs without glibc
+	native, other := resolveAll("apk", packages)
+
+	if len(native) > 0 {
+		for _, r := range native {
+			if r.Community {
+				if err := ensureApkCommunityRepo(); err != nil {
+					return fmt.Errorf("failed to enable the community repository: %w", err)
+				}
+				break
+			}
+		}
+
+		updateCmd := sudoCommand("apk", "update")
+		updateCmd.Stdout = stdout
+		updateCmd.Stderr = stderr
+		fmt.Fprintln(stdout, "Running: apk update")
+		if err := runCommand(updateCmd); err != nil {
+			return fmt.Errorf("failed to update package index: %w", err)
+		}
+
+		names := recipeNames(native)
+		cmd := sudoCommand("apk", append([]string{"add"}, names...)...)
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+
+		fmt.Fprintf(stdout, "Running: apk add %v\n", names)
+		if err := runCommand(cmd); err != nil {
+			return fmt.Errorf("failed to install %v: %w", names, err)
+		}
+
+		// Alpine uses OpenRC rather than systemd
+		for _, service := range recipeServices(native) {
+			if err := enableOpenRCService(service); err != nil {
+				return fmt.Errorf("failed to start %s service: %w", service, err)
+			}
+		}
+	}
+
+	return installOther(other)
+}
+
+// enableOpenRCService adds an OpenRC service to the default runlevel and starts it
+func enableOpenRCService(service string) error {
+	if !IsCommandAvailable("rc-update") {
+		fmt.Fprintf(stdout, "OpenRC not found; start %s yourself (for example: %sd &)\n", service, service)
+		return nil
+	}
+
+	addCmd := sudoCommand("rc-update", "add", service, "default")
+	addCmd.Stdout = stdout
+	addCmd.Stderr = stderr
+	if err := runCommand(addCmd); err != nil {
+		return err
+	}
+
+	startCmd := sudoCommand("rc-service", service, "start")
+	startCmd.Stdout = stdout
+	startCmd.Stderr = stderr
+	return runCommand(startCmd)
+}
+
+func (a *ApkManager) IsInstalled(pkg string) bool {
+	r := resolve("apk", pkg)
+	if r.Method != methodPackage {
+		return otherInstalled(r)
+	}
+	_, err := queryOutput("apk", "info", "-e", r.Name)
+	return err == nil
+}
+
+func (a *ApkManager) Version(pkg string) (string, error) {
+	r := resolve("apk", pkg)
+	if r.Method != methodPackage {
+		return otherVersion(r)
+	}
+
+	// apk list prints "name-version-release arch {origin} (license) [installed]"
+	out, err := queryOutput("apk", "list", "--installed", r.Name)
+	if fields := strings.Fields(out); err == nil && len(fields) > 0 {
+		if version, ok := strings.CutPrefix(fields[0], r.Name+"-"); ok {
+			return version, nil
+		}
+	}
+	return "", fmt.Errorf("%s: %w", pkg, ErrNotInstalled)
+}
+
+func (a *ApkManager) Upgrade(packages ...string) error {
+	native, other := resolveAll("apk", packages)
+
+	if len(native) > 0 {
+		updateCmd := sudoCommand("apk", "update")
+		updateCmd.Stdout = stdout
+		updateCmd.Stderr = stderr
+		fmt.Fprintln(stdout, "Running: apk update")
+		if err := runCommand(updateCmd); err != nil {
+			return fmt.Errorf("failed to update package index: %w", err)
+		}
+
+		names := recipeNames(native)
+		cmd := sudoCommand("apk", append([]string{"add", "--upgrade"}, names...)...)
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+
+		fmt.Fprintf(stdout, "Running: apk add --upgrade %v\n", names)
+		if err := runCommand(cmd); err != nil {
+			return fmt.Errorf("failed to upgrade %v: %w", names, err)
+		}
+	}
+
+	// The 1Password CLI binary is replaced with the latest release
+	return installOther(other)
+}
+
+func (a *ApkManager) Remove(packages ...string) error {
+	native, other := resolveAll("apk", packages)
+
+	if len(native) > 0 {
+		names := recipeNames(native)
+		cmd := sudoCommand("apk", append([]string{"del"}, names...)...)
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+
+		fmt.Fprintf(stdout, "Running: apk del %v\n", names)
+		if err := runCommand(cmd); err != nil {
+			return fmt.Errorf("failed to remove %v: %w", names, err)
+		}
+	}
+
+	return removeOther(other)
+}
@@ -0,0 +1,233 @@
+package installer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const (
+	apkRepositories = "/etc/apk/repositories"
+	opBinaryPath    = "/usr/local/bin/op"
+	// opSigningKey is the key 1Password signs its packages and CLI binaries with
+	opSigningKey = "https://downloads.1password.com/linux/keys/1password.asc"
+	// opUpdateCheck reports the latest 1Password CLI v2 release
+	opUpdateCheck = "https://app-updates.agilebits.com/check/1/0/CLI2/en/2.0.0/N"
+)
+
+// ensureApkCommunityRepo enables the community repository, derived from the main one, if it isn't already
+func ensureApkCommunityRepo() error {
+	data, err := files.ReadFile(apkRepositories)
+	if err != nil {
+		return err
+	}
+
+	var mainRepo string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(strings.TrimSpace(line), "/")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasSuffix(line, "/community") {
+			return nil
+		}
+		if strings.HasSuffix(line, "/main") {
+			mainRepo = line
+		}
+	}
+	if mainRepo == "" {
+		return fmt.Errorf("no main repository in %s to derive it from", apkRepositories)
+	}
+
+	community := strings.TrimSuffix(mainRepo, "main") + "community"
+	fmt.Fprintf(stdout, "Enabling community repository %s\n", community)
+
+	cmd := sudoCommand("tee", "-a", apkRepositories)
+	cmd.Stdin = strings.NewReader(community + "\n")
+	cmd.Stdout = io.Discard
+	cmd.Stderr = stderr
+	return trackFile(apkRepositories, func() error {
+		return runAction(Action{Kind: ActionRepo, Summary: "Add apk repository " + community, Path: apkRepositories}, cmd)
+	})
+}
+
+// Install1PasswordCLIBinary installs the latest 1Password CLI release binary to /usr/local/bin.
+// The binary is only installed once its signature is verified against 1Password's signing key.
+func Install1PasswordCLIBinary() error {
+	arch := runtime.GOARCH
+	switch arch {
+	case "amd64", "arm64", "386", "arm":
+	default:
+		return fmt.Errorf("no 1Password CLI release for linux/%s", arch)
+	}
+
+	if err := ensureGPG(); err != nil {
+		return err
+	}
+
+	if planner != nil {
+		planner.record(Action{Kind: ActionDownload, Summary: fmt.Sprintf("Download the latest 1Password CLI release for linux/%s", arch), Path: opBinaryPath})
+		return nil
+	}
+
+	version, err := latest1PasswordCLIVersion()
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("https://cache.agilebits.com/dist/1P/op2/pkg/v%s/op_linux_%s_v%s.zip", version, arch, version)
+	fmt.Fprintf(stdout, "Downloading 1Password CLI %s...\n", version)
+	archive, err := httpGet(url)
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "op-install-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	if err := extractZip(archive, dir, "op", "op.sig"); err != nil {
+		return fmt.Errorf("failed to extract %s: %w", url, err)
+	}
+	if err := verify1PasswordSignature(dir); err != nil {
+		return err
+	}
+
+	cmd := sudoCommand("install", "-m", "0755", filepath.Join(dir, "op"), opBinaryPath)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := runAction(Action{Kind: ActionFile, Summary: "Install op binary", Path: opBinaryPath}, cmd); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(stdout, "✓ 1Password CLI installed successfully")
+	return nil
+}
+
+// latest1PasswordCLIVersion asks 1Password's update service for the current CLI version
+func latest1PasswordCLIVersion() (string, error) {
+	body, err := httpGet(opUpdateCheck)
+	if err != nil {
+		return "", err
+	}
+
+	var check struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(body, &check); err != nil || check.Version == "" {
+		return "", fmt.Errorf("failed to determine the latest 1Password CLI version")
+	}
+	return check.Version, nil
+}
+
+// ensureGPG installs gnupg when gpg is missing, since the 1Password CLI binary is never installed unverified
+func ensureGPG() error {
+	if IsCommandAvailable("gpg") {
+		return nil
+	}
+	if !IsCommandAvailable("apk") {
+		return fmt.Errorf("gpg is required to verify the 1Password CLI signature; install gnupg and try again")
+	}
+
+	cmd := sudoCommand("apk", "add", "--no-cache", "gnupg")
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	fmt.Fprintln(stdout, "Running: apk add gnupg")
+	if err := runCommand(cmd); err != nil {
+		return fmt.Errorf("failed to install gnupg to verify the 1Password CLI signature: %w", err)
+	}
+	return nil
+}
+
+// verify1PasswordSignature checks dir/op against dir/op.sig in a throwaway keyring.
+// The downloaded key comes from the same host as the binary, so it must be the key with the fingerprint in the catalog.
+func verify1PasswordSignature(dir string) error {
+	key, err := httpGet(opSigningKey)
+	if err != nil {
+		return err
+	}
+
+	home := filepath.Join(dir, "gnupg")
+	if err := os.Mkdir(home, 0700); err != nil {
+		return err
+	}
+
+	importCmd := command("gpg", "--batch", "--homedir", home, "--import")
+	importCmd.Stdin = bytes.NewReader(key)
+	if out, err := combinedOutput(importCmd); err != nil {
+		return fmt.Errorf("failed to import 1Password signing key: %w: %s", err, out)
+	}
+
+	listing, err := output(command("gpg", "--batch", "--with-colons", "--homedir", home, "--list-keys"))
+	if err != nil {
+		return fmt.Errorf("failed to list the imported 1Password signing key: %w", err)
+	}
+	if err := checkFingerprint(string(listing), tools.Tools["1password-cli"].AUR.Fingerprint); err != nil {
+		return fmt.Errorf("signing key from %s: %w", opSigningKey, err)
+	}
+
+	verifyCmd := command("gpg", "--batch", "--homedir", home, "--verify", filepath.Join(dir, "op.sig"), filepath.Join(dir, "op"))
+	if out, err := combinedOutput(verifyCmd); err != nil {
+		return fmt.Errorf("1Password CLI signature verification failed: %w: %s", err, out)
+	}
+	fmt.Fprintln(stdout, "✓ Verified 1Password CLI signature")
+	return nil
+}
+
+// extractZip writes the named files from a zip archive into dir
+func extractZip(archive []byte, dir string, names ...string) error {
+	r, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		f, err := r.Open(name)
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0755); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// httpClient downloads keys, repository files and install scripts
+var httpClient = http.DefaultClient
+
+// SetHTTPClient makes the installer download with c; nil restores http.DefaultClient
+func SetHTTPClient(c *http.Client) {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	httpClient = c
+}
+
+// httpGet downloads url into memory
+func httpGet(url string) ([]byte, error) {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: HTTP %d", url, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
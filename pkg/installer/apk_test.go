@@ -0,0 +1,81 @@
+package installer_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/whexy/wenxuan-dev-init/pkg/installer"
+	"github.com/whexy/wenxuan-dev-init/pkg/installer/installertest"
+)
+
+// keyListing is `gpg --with-colons --list-keys` output for a keyring holding one key with fingerprint fpr
+func keyListing(fpr string) string {
+	return fmt.Sprintf("tru::1:1700000000:0:3:1:5\npub:-:4096:1:%s:1608767582:::-:::scESC::::::23::0:\nfpr:::::::::%s:\n", fpr[len(fpr)-16:], fpr)
+}
+
+// serve1PasswordRelease makes the fakes serve version 2.30.0 of the CLI for this architecture, signed with key
+func serve1PasswordRelease(t *testing.T, f *fakes, key string) {
+	t.Helper()
+	var archive bytes.Buffer
+	w := zip.NewWriter(&archive)
+	for _, name := range []string{"op", "op.sig"} {
+		file, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file.Write([]byte(name + " contents"))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f.transport.Serve("https://app-updates.agilebits.com/check/1/0/CLI2/en/2.0.0/N", `{"version": "2.30.0"}`)
+	f.transport.Serve(fmt.Sprintf("https://cache.agilebits.com/dist/1P/op2/pkg/v2.30.0/op_linux_%s_v2.30.0.zip", runtime.GOARCH), archive.String())
+	f.transport.Serve("https://downloads.1password.com/linux/keys/1password.asc", key)
+}
+
+func TestInstall1PasswordCLIBinary(t *testing.T) {
+	if runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64" {
+		t.Skipf("no 1Password CLI release for %s", runtime.GOARCH)
+	}
+
+	tests := []struct {
+		name        string
+		fingerprint string
+		wantErr     string
+	}{
+		{name: "1Password key", fingerprint: "3FEF9748469ADBE15DA7CA80AC2D62742012EA22"},
+		{name: "other key", fingerprint: "FEDCBA98765432100123456789ABCDEF12345678", wantErr: "expected exactly the key with fingerprint 3FEF9748469ADBE15DA7CA80AC2D62742012EA22"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakes(t, "gpg")
+			serve1PasswordRelease(t, f, "armored key")
+			f.runner.Respond("gpg --batch --with-colons", installertest.Response{Stdout: keyListing(tt.fingerprint)})
+
+			err := installer.Install1PasswordCLIBinary()
+			installed := slices.ContainsFunc(f.runner.Commands(), func(c string) bool { return strings.HasPrefix(c, "sudo install ") })
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Install1PasswordCLIBinary() error = %v, want %q", err, tt.wantErr)
+				}
+				if installed {
+					t.Errorf("op was installed with an unverified signature: %q", f.runner.Commands())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !installed {
+				t.Errorf("op was not installed: %q", f.runner.Commands())
+			}
+		})
+	}
+}
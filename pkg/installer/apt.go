@@ -2,8 +2,7 @@ package installer
 
 import (
 	"fmt"
-	"os"
-	"os/exec"
+	"strings"
 )
 
 type AptManager struct{}
@@ -21,69 +20,115 @@ func (a *AptManager) IsAvailable() bool {
 }
 
 func (a *AptManager) Install(packages ...string) error {
-	// Separate packages that need special handling
-	var standardPackages []string
-	var needsGH, needs1Password, needsChezmoi bool
-
-	for _, pkg := range packages {
-		switch pkg {
-		case "gh":
-			needsGH = true
-		case "1password-cli":
-			needs1Password = true
-		case "chezmoi":
-			needsChezmoi = true
-		default:
-			standardPackages = append(standardPackages, pkg)
+	native, other := resolveAll("apt", packages)
+
+	if len(native) > 0 {
+		// gh, 1password-cli and tailscale come from vendor repositories
+		if repos := recipeRepos(native); len(repos) > 0 {
+			if err := ensurePrerequisites(); err != nil {
+				return err
+			}
+			vars := repoVars()
+			for _, id := range repos {
+				repo := tools.Repos.Apt[id]
+				if err := addAptRepo(id, repo, vars); err != nil {
+					return fmt.Errorf("failed to add %s repository: %w", repo.Name, err)
+				}
+			}
 		}
-	}
 
-	// Install standard packages first
-	if len(standardPackages) > 0 {
 		// Update package list first
-		updateCmd := exec.Command("sudo", "apt-get", "update")
-		updateCmd.Stdout = os.Stdout
-		updateCmd.Stderr = os.Stderr
-		fmt.Println("Running: sudo apt-get update")
-		if err := updateCmd.Run(); err != nil {
+		updateCmd := sudoCommand("apt-get", "update")
+		updateCmd.Stdout = stdout
+		updateCmd.Stderr = stderr
+		fmt.Fprintln(stdout, "Running: sudo apt-get update")
+		if err := runCommand(updateCmd); err != nil {
 			return fmt.Errorf("failed to update package list: %w", err)
 		}
 
-		// Install standard packages
-		args := append([]string{"apt-get", "install", "-y"}, standardPackages...)
-		cmd := exec.Command("sudo", args...)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
+		names := recipeNames(native)
+		args := append([]string{"install", "-y"}, names...)
+		cmd := sudoCommand("apt-get", args...)
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
 
-		fmt.Printf("Running: sudo apt-get install -y %v\n", standardPackages)
-		if err := cmd.Run(); err != nil {
-			fmt.Printf("Warning: Some standard packages failed to install: %v\n", err)
+		fmt.Fprintf(stdout, "Running: sudo apt-get install -y %v\n", names)
+		if err := runCommand(cmd); err != nil {
+			return fmt.Errorf("failed to install %v: %w", names, err)
 		}
-	}
 
-	// Install GitHub CLI with repository setup
-	if needsGH {
-		fmt.Println("\n📦 Installing GitHub CLI (requires repository setup)...")
-		if err := InstallGitHubCLI(); err != nil {
-			return fmt.Errorf("failed to install GitHub CLI: %w", err)
+		for _, service := range recipeServices(native) {
+			if err := enableSystemdService(service); err != nil {
+				return err
+			}
 		}
 	}
 
-	// Install 1Password CLI with repository setup
-	if needs1Password {
-		fmt.Println("\n📦 Installing 1Password CLI (requires repository setup)...")
-		if err := Install1PasswordCLI(); err != nil {
-			return fmt.Errorf("failed to install 1Password CLI: %w", err)
+	// chezmoi uses its official installer
+	return installOther(other)
+}
+
+func (a *AptManager) IsInstalled(pkg string) bool {
+	r := resolve("apt", pkg)
+	if r.Method != methodPackage {
+		return otherInstalled(r)
+	}
+	status, err := queryOutput("dpkg-query", "-W", "-f=${Status}", r.Name)
+	return err == nil && strings.HasSuffix(status, " installed")
+}
+
+func (a *AptManager) Version(pkg string) (string, error) {
+	r := resolve("apt", pkg)
+	if r.Method != methodPackage {
+		return otherVersion(r)
+	}
+	if !a.IsInstalled(pkg) {
+		return "", fmt.Errorf("%s: %w", pkg, ErrNotInstalled)
+	}
+	return queryOutput("dpkg-query", "-W", "-f=${Version}", r.Name)
+}
+
+func (a *AptManager) Upgrade(packages ...string) error {
+	native, other := resolveAll("apt", packages)
+
+	if len(native) > 0 {
+		updateCmd := sudoCommand("apt-get", "update")
+		updateCmd.Stdout = stdout
+		updateCmd.Stderr = stderr
+		fmt.Fprintln(stdout, "Running: sudo apt-get update")
+		if err := runCommand(updateCmd); err != nil {
+			return fmt.Errorf("failed to update package list: %w", err)
+		}
+
+		names := recipeNames(native)
+		cmd := sudoCommand("apt-get", append([]string{"install", "-y", "--only-upgrade"}, names...)...)
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+
+		fmt.Fprintf(stdout, "Running: sudo apt-get install -y --only-upgrade %v\n", names)
+		if err := runCommand(cmd); err != nil {
+			return fmt.Errorf("failed to upgrade %v: %w", names, err)
 		}
 	}
 
-	// Install chezmoi with official installer
-	if needsChezmoi {
-		fmt.Println("\n📦 Installing chezmoi (using official installer)...")
-		if err := InstallChezmoi(); err != nil {
-			return fmt.Errorf("failed to install chezmoi: %w", err)
+	// Vendor installers fetch the latest release
+	return installOther(other)
+}
+
+func (a *AptManager) Remove(packages ...string) error {
+	native, other := resolveAll("apt", packages)
+
+	if len(native) > 0 {
+		names := recipeNames(native)
+		cmd := sudoCommand("apt-get", append([]string{"remove", "-y"}, names...)...)
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+
+		fmt.Fprintf(stdout, "Running: sudo apt-get remove -y %v\n", names)
+		if err := runCommand(cmd); err != nil {
+			return fmt.Errorf("failed to remove %v: %w", names, err)
 		}
 	}
 
-	return nil
+	return removeOther(other)
 }
@@ -1,9 +1,10 @@
 package installer
 
 import (
+	"bytes"
 	"fmt"
-	"os"
-	"os/exec"
+	"io"
+	"strings"
 )
 
 // ensurePrerequisites makes sure required tools are installed
@@ -22,15 +23,15 @@ func ensurePrerequisites() error {
 	}
 
 	if len(prereqs) > 0 {
-		fmt.Printf("Installing prerequisites: %v\n", prereqs)
-		updateCmd := exec.Command("sudo", "apt-get", "update", "-qq")
-		updateCmd.Run() // Ignore errors
-
-		args := append([]string{"apt-get", "install", "-y"}, prereqs...)
-		cmd := exec.Command("sudo", args...)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		if err := cmd.Run(); err != nil {
+		fmt.Fprintf(stdout, "Installing prerequisites: %v\n", prereqs)
+		updateCmd := sudoCommand("apt-get", "update", "-qq")
+		runCommand(updateCmd) // Ignore errors
+
+		args := append([]string{"install", "-y"}, prereqs...)
+		cmd := sudoCommand("apt-get", args...)
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+		if err := runCommand(cmd); err != nil {
 			return fmt.Errorf("failed to install prerequisites: %w", err)
 		}
 	}
@@ -38,117 +39,51 @@ func ensurePrerequisites() error {
 	return nil
 }
 
-// InstallGitHubCLI installs GitHub CLI with proper repository setup
-func InstallGitHubCLI() error {
-	// Ensure prerequisites
-	if err := ensurePrerequisites(); err != nil {
-		return err
-	}
-
-	fmt.Println("Setting up GitHub CLI repository...")
-
-	// Add GitHub CLI repository
-	cmd1 := exec.Command("bash", "-c", "curl -fsSL https://cli.github.com/packages/githubcli-archive-keyring.gpg | sudo dd of=/usr/share/keyrings/githubcli-archive-keyring.gpg")
-	cmd1.Stdout = os.Stdout
-	cmd1.Stderr = os.Stderr
-	if err := cmd1.Run(); err != nil {
-		return fmt.Errorf("failed to add GitHub CLI keyring: %w", err)
-	}
-
-	cmd2 := exec.Command("bash", "-c", "echo \"deb [arch=$(dpkg --print-architecture) signed-by=/usr/share/keyrings/githubcli-archive-keyring.gpg] https://cli.github.com/packages stable main\" | sudo tee /etc/apt/sources.list.d/github-cli.list")
-	cmd2.Stdout = os.Stdout
-	cmd2.Stderr = os.Stderr
-	if err := cmd2.Run(); err != nil {
-		return fmt.Errorf("failed to add GitHub CLI repository: %w", err)
-	}
-
-	// Update and install
-	updateCmd := exec.Command("sudo", "apt-get", "update")
-	updateCmd.Stdout = os.Stdout
-	updateCmd.Stderr = os.Stderr
-	if err := updateCmd.Run(); err != nil {
-		return fmt.Errorf("failed to update after adding repo: %w", err)
-	}
-
-	installCmd := exec.Command("sudo", "apt-get", "install", "-y", "gh")
-	installCmd.Stdout = os.Stdout
-	installCmd.Stderr = os.Stderr
-	if err := installCmd.Run(); err != nil {
-		return fmt.Errorf("failed to install gh: %w", err)
-	}
-
-	fmt.Println("✓ GitHub CLI installed successfully")
-	return nil
+// aptRepo is a vendor apt repository
+type aptRepo struct {
+	Name string `yaml:"name"`
+	Key  string `yaml:"key"`
+	// Dearmor converts an ASCII-armored key to the binary keyring apt expects
+	Dearmor bool   `yaml:"dearmor"`
+	Keyring string `yaml:"keyring"`
+	// Source is the sources.list line; {keyring} is replaced with Keyring
+	Source string `yaml:"source"`
 }
 
-// Install1PasswordCLI installs 1Password CLI with proper repository setup
-func Install1PasswordCLI() error {
-	// Ensure prerequisites
-	if err := ensurePrerequisites(); err != nil {
-		return err
+// addAptRepo installs the repository's signing key and adds it to /etc/apt/sources.list.d unless it is already there
+func addAptRepo(id string, repo aptRepo, vars *strings.Replacer) error {
+	list := "/etc/apt/sources.list.d/" + id + ".list"
+	if _, err := files.Stat(list); err == nil {
+		return nil
 	}
 
-	fmt.Println("Setting up 1Password CLI repository...")
-
-	// Add 1Password repository
-	cmd1 := exec.Command("bash", "-c", "curl -sS https://downloads.1password.com/linux/keys/1password.asc | sudo gpg --dearmor --output /usr/share/keyrings/1password-archive-keyring.gpg")
-	cmd1.Stdout = os.Stdout
-	cmd1.Stderr = os.Stderr
-	if err := cmd1.Run(); err != nil {
-		return fmt.Errorf("failed to add 1Password keyring: %w", err)
-	}
+	fmt.Fprintf(stdout, "Setting up %s repository...\n", repo.Name)
 
-	cmd2 := exec.Command("bash", "-c", "echo 'deb [arch=amd64 signed-by=/usr/share/keyrings/1password-archive-keyring.gpg] https://downloads.1password.com/linux/debian/amd64 stable main' | sudo tee /etc/apt/sources.list.d/1password.list")
-	cmd2.Stdout = os.Stdout
-	cmd2.Stderr = os.Stderr
-	if err := cmd2.Run(); err != nil {
-		return fmt.Errorf("failed to add 1Password repository: %w", err)
-	}
-
-	// Update and install
-	updateCmd := exec.Command("sudo", "apt-get", "update")
-	updateCmd.Stdout = os.Stdout
-	updateCmd.Stderr = os.Stderr
-	if err := updateCmd.Run(); err != nil {
-		return fmt.Errorf("failed to update after adding repo: %w", err)
-	}
-
-	installCmd := exec.Command("sudo", "apt-get", "install", "-y", "1password-cli")
-	installCmd.Stdout = os.Stdout
-	installCmd.Stderr = os.Stderr
-	if err := installCmd.Run(); err != nil {
-		return fmt.Errorf("failed to install 1password-cli: %w", err)
-	}
-
-	fmt.Println("✓ 1Password CLI installed successfully")
-	return nil
-}
-
-// InstallChezmoi installs chezmoi using the official installer
-func InstallChezmoi() error {
-	// Ensure curl is available
-	if !IsCommandAvailable("curl") {
-		fmt.Println("Installing curl...")
-		cmd := exec.Command("sudo", "apt-get", "install", "-y", "curl")
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("failed to install curl: %w", err)
+	keyURL := vars.Replace(repo.Key)
+	var key []byte
+	if planner == nil {
+		var err error
+		if key, err = httpGet(keyURL); err != nil {
+			return err
 		}
 	}
 
-	fmt.Println("Installing chezmoi to /usr/local/bin...")
-
-	// Use the official installer with binary install to /usr/local/bin
-	cmd := exec.Command("sh", "-c", "curl -fsLS get.chezmoi.io | sudo sh -s -- -b /usr/local/bin")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Env = os.Environ()
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to install chezmoi: %w", err)
+	keyCmd := sudoCommand("dd", "of="+repo.Keyring, "status=none")
+	if repo.Dearmor {
+		keyCmd = sudoCommand("gpg", "--dearmor", "--yes", "--output", repo.Keyring)
+	}
+	keyCmd.Stdin = bytes.NewReader(key)
+	keyCmd.Stdout = io.Discard
+	keyCmd.Stderr = stderr
+	err := trackFile(repo.Keyring, func() error {
+		return runAction(Action{Kind: ActionFile, Summary: fmt.Sprintf("Download %s signing key %s", repo.Name, keyURL), Path: repo.Keyring}, keyCmd)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add %s keyring: %w", repo.Name, err)
 	}
 
-	fmt.Println("✓ Chezmoi installed successfully")
-	return nil
+	source := strings.ReplaceAll(vars.Replace(repo.Source), "{keyring}", repo.Keyring)
+	return trackFile(list, func() error {
+		return writeRootFile(list, source+"\n", Action{Kind: ActionRepo, Summary: "Add apt repository " + source})
+	})
 }
@@ -0,0 +1,181 @@
+package installer_test
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/whexy/wenxuan-dev-init/pkg/installer"
+	"github.com/whexy/wenxuan-dev-init/pkg/installer/installertest"
+)
+
+const ubuntuRelease = `NAME="Ubuntu"
+ID=ubuntu
+VERSION_ID="24.04"
+VERSION_CODENAME=noble
+`
+
+// fakes replaces the installer's commands, files, command lookup and downloads for the test
+type fakes struct {
+	runner    *installertest.Runner
+	files     *installertest.FileSystem
+	transport *installertest.Transport
+}
+
+// newFakes makes the installer run against fakes on a machine that has commands; everything is restored when the test ends
+func newFakes(t *testing.T, commands ...string) *fakes {
+	t.Helper()
+	f := &fakes{
+		runner:    installertest.NewRunner(),
+		files:     installertest.NewFileSystem(t.TempDir()),
+		transport: installertest.NewTransport(),
+	}
+	installer.SetRunner(f.runner)
+	installer.SetFileSystem(f.files)
+	installer.SetLookPath(installertest.LookPath(commands...))
+	installer.SetHTTPClient(&http.Client{Transport: f.transport})
+	t.Cleanup(installer.SetOutput(io.Discard))
+	t.Cleanup(func() {
+		installer.SetRunner(nil)
+		installer.SetFileSystem(nil)
+		installer.SetLookPath(nil)
+		installer.SetHTTPClient(nil)
+	})
+	return f
+}
+
+func (f *fakes) write(t *testing.T, name, content string) {
+	t.Helper()
+	if err := f.files.Write(name, content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+const ghKeyURL = "https://cli.github.com/packages/githubcli-archive-keyring.gpg"
+
+func TestAptInstallAddsVendorRepository(t *testing.T) {
+	f := newFakes(t, "apt-get", "curl", "gpg", "sudo")
+	f.write(t, "/etc/os-release", ubuntuRelease)
+	f.transport.Serve(ghKeyURL, "gh keyring")
+
+	if err := installer.NewAptManager().Install("git", "gh"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"sudo dd of=/usr/share/keyrings/githubcli-archive-keyring.gpg status=none",
+		"sudo tee /etc/apt/sources.list.d/github-cli.list",
+		"sudo apt-get update",
+		"sudo apt-get install -y git gh",
+	}
+	if got := f.runner.Commands(); !slices.Equal(got, want) {
+		t.Fatalf("commands = %q, want %q", got, want)
+	}
+
+	calls := f.runner.Calls()
+	if calls[0].Stdin != "gh keyring" {
+		t.Errorf("keyring written from %q, want the downloaded key", calls[0].Stdin)
+	}
+	if source := calls[1].Stdin; !strings.HasPrefix(source, "deb [arch=") || !strings.Contains(source, "signed-by=/usr/share/keyrings/githubcli-archive-keyring.gpg] https://cli.github.com/packages stable main") {
+		t.Errorf("sources.list entry = %q", source)
+	}
+	if got := f.transport.URLs(); !slices.Equal(got, []string{ghKeyURL}) {
+		t.Errorf("downloads = %q, want the signing key", got)
+	}
+}
+
+func TestAptInstallKeepsExistingRepository(t *testing.T) {
+	f := newFakes(t, "apt-get", "curl", "gpg", "sudo")
+	f.write(t, "/etc/os-release", ubuntuRelease)
+	f.write(t, "/etc/apt/sources.list.d/github-cli.list", "deb https://cli.github.com/packages stable main\n")
+
+	if err := installer.NewAptManager().Install("gh"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"sudo apt-get update", "sudo apt-get install -y gh"}
+	if got := f.runner.Commands(); !slices.Equal(got, want) {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+	if len(f.transport.URLs()) != 0 {
+		t.Errorf("downloads = %q, want none", f.transport.URLs())
+	}
+}
+
+func TestAptInstallPrerequisites(t *testing.T) {
+	f := newFakes(t, "apt-get", "sudo")
+	f.write(t, "/etc/os-release", ubuntuRelease)
+	f.write(t, "/etc/apt/sources.list.d/github-cli.list", "")
+
+	if err := installer.NewAptManager().Install("gh"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"sudo apt-get update -qq",
+		"sudo apt-get install -y curl gnupg",
+		"sudo apt-get update",
+		"sudo apt-get install -y gh",
+	}
+	if got := f.runner.Commands(); !slices.Equal(got, want) {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+}
+
+func TestAptInstallFailureKeepsOutput(t *testing.T) {
+	f := newFakes(t, "apt-get")
+	f.runner.Fail("apt-get install", 100, "E: Unable to locate package gti\n")
+
+	err := installer.NewAptManager().Install("gti")
+	if err == nil || !strings.Contains(err.Error(), "E: Unable to locate package gti") {
+		t.Fatalf("Install() error = %v, want apt-get's message", err)
+	}
+	if got := f.runner.Commands(); len(got) != 2 {
+		t.Errorf("commands = %q, want the update and the failed install", got)
+	}
+}
+
+func TestRemoveFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	f := newFakes(t)
+	rc := filepath.Join(home, ".bashrc")
+	f.write(t, rc, "export PATH\n")
+	f.write(t, "/etc/apt/sources.list.d/github-cli.list", "")
+
+	// Files in the home directory are removed directly, and others with sudo
+	for _, path := range []string{rc, "/etc/apt/sources.list.d/github-cli.list", "/etc/missing.list"} {
+		if err := installer.RemoveFile(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := os.Stat(f.files.Path(rc)); !os.IsNotExist(err) {
+		t.Errorf("%s still exists", rc)
+	}
+	want := []string{"sudo rm -f /etc/apt/sources.list.d/github-cli.list"}
+	if got := f.runner.Commands(); !slices.Equal(got, want) {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+}
+
+func TestRemoveAppend(t *testing.T) {
+	f := newFakes(t)
+	f.write(t, "/home/wx/.bashrc", "alias ll='ls -l'\neval \"$(devbox global shellenv)\"\nexport EDITOR=vim\n")
+
+	if err := installer.RemoveAppend("/home/wx/.bashrc", "eval \"$(devbox global shellenv)\"\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(f.files.Path("/home/wx/.bashrc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "alias ll='ls -l'\nexport EDITOR=vim\n"; string(data) != want {
+		t.Errorf(".bashrc = %q, want %q", data, want)
+	}
+}
@@ -0,0 +1,34 @@
+package installer
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestKeyFingerprints(t *testing.T) {
+	// `gpg --with-colons --show-keys` for 1Password's signing key
+	onePassword := `pub:-:4096:1:AC2D62742012EA22:1608767582:::-:::scESC::::::23::0:
+fpr:::::::::3FEF9748469ADBE15DA7CA80AC2D62742012EA22:
+uid:-::::1608767582::53E6B6E3BA5D2B4F6AEB9A0A5A0B4B5E7DD1F2B0::Code signing for 1Password <codesign@1password.com>::::::::::0:
+sub:-:4096:1:6A2D1E3F6D0B62E7:1608767582::::::e::::::23:
+fpr:::::::::0A1B2C3D4E5F60718293A4B5C6D7E8F96A2D1E3F:
+`
+	other := `pub:-:255:22:1234567890ABCDEF:1700000000:::-:::scESC::::::ed25519::0:
+fpr:::::::::FEDCBA98765432100123456789ABCDEF12345678:
+`
+
+	tests := []struct {
+		name    string
+		listing string
+		want    []string
+	}{
+		{"one key", onePassword, []string{"3FEF9748469ADBE15DA7CA80AC2D62742012EA22"}},
+		{"two keys", onePassword + other, []string{"3FEF9748469ADBE15DA7CA80AC2D62742012EA22", "FEDCBA98765432100123456789ABCDEF12345678"}},
+		{"empty", "", nil},
+	}
+	for _, tt := range tests {
+		if got := keyFingerprints(tt.listing); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: keyFingerprints() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -1,16 +1,21 @@
 package installer
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
 	"os"
-	"os/exec"
 	"strings"
+
+	"github.com/whexy/wenxuan-dev-init/pkg/events"
+	"github.com/whexy/wenxuan-dev-init/pkg/state"
+	"github.com/whexy/wenxuan-dev-init/pkg/ui"
 )
 
 var (
 	githubTokenReference = "op://Developer/GitHub Personal Access Token/token"
 	useServiceAccount    = false
+	nonInteractive       = false
 )
 
 // SetGitHubTokenReference sets the 1Password reference for GitHub token
@@ -23,6 +28,11 @@ func SetUseServiceAccount(use bool) {
 	useServiceAccount = use
 }
 
+// SetNonInteractive disables reading from the terminal; anything that would prompt fails instead
+func SetNonInteractive(enabled bool) {
+	nonInteractive = enabled
+}
+
 // Login1Password prompts the user to log in to 1Password
 func Login1Password() error {
 	// If using service account, check for token
@@ -30,17 +40,22 @@ func Login1Password() error {
 		return ensureServiceAccountToken()
 	}
 
+	if nonInteractive {
+		return fmt.Errorf("interactive 1Password sign-in is not possible in non-interactive mode; use --use-service-account with OP_SERVICE_ACCOUNT_TOKEN")
+	}
+
 	// Regular interactive signin
-	fmt.Println("Logging in to 1Password...")
-	fmt.Println("Please follow the prompts to authenticate.")
+	fmt.Fprintln(stdout, "Logging in to 1Password...")
 
 	// Use --force flag to bypass the eval warning
-	cmd := exec.Command("op", "signin", "--force")
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd := command("op", "signin", "--force")
 
-	return cmd.Run()
+	before := opAccounts()
+	if err := runInteractive(cmd, "Please follow the prompts to authenticate."); err != nil {
+		return err
+	}
+	rememberNewOPAccounts(before)
+	return nil
 }
 
 // ensureServiceAccountToken checks for OP_SERVICE_ACCOUNT_TOKEN and prompts if not set
@@ -48,18 +63,35 @@ func ensureServiceAccountToken() error {
 	token := os.Getenv("OP_SERVICE_ACCOUNT_TOKEN")
 
 	if token != "" {
-		fmt.Println("✓ Using 1Password service account token from environment")
+		fmt.Fprintln(stdout, "✓ Using 1Password service account token from environment")
+		return nil
+	}
+
+	if nonInteractive {
+		return fmt.Errorf("OP_SERVICE_ACCOUNT_TOKEN is not set; secrets are never read from a prompt in non-interactive mode")
+	}
+
+	if planner != nil {
+		planner.record(Action{Kind: ActionPrompt, Summary: "Prompt for OP_SERVICE_ACCOUNT_TOKEN"})
 		return nil
 	}
 
 	// Token not set, prompt user
-	fmt.Println("1Password service account mode enabled, but OP_SERVICE_ACCOUNT_TOKEN is not set.")
-	fmt.Println("")
-	fmt.Println("Please enter your 1Password service account token:")
-	fmt.Print("> ")
+	fmt.Fprintln(stdout, "1Password service account mode enabled, but OP_SERVICE_ACCOUNT_TOKEN is not set.")
 
 	var inputToken string
-	fmt.Scanln(&inputToken)
+	events.Publish(events.Event{Kind: events.PromptRequested, Prompt: "1Password service account token"})
+	err := ui.WithTerminal(func() error {
+		fmt.Println("Please enter your 1Password service account token:")
+		fmt.Print("> ")
+		// An empty line is not an error here; it is reported as a missing token below
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		inputToken = strings.TrimSpace(line)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read service account token: %w", err)
+	}
 
 	if inputToken == "" {
 		return fmt.Errorf("no service account token provided")
@@ -67,26 +99,19 @@ func ensureServiceAccountToken() error {
 
 	// Set the environment variable for current process and children
 	os.Setenv("OP_SERVICE_ACCOUNT_TOKEN", inputToken)
-	fmt.Println("✓ Service account token set successfully")
+	fmt.Fprintln(stdout, "✓ Service account token set successfully")
 
 	return nil
 }
 
 // GetGitHubTokenFrom1Password retrieves the GitHub token from 1Password
 func GetGitHubTokenFrom1Password() (string, error) {
-	fmt.Printf("Fetching GitHub token from 1Password: %s\n", githubTokenReference)
-
-	// Read the secret from 1Password
-	cmd := exec.Command("op", "read", githubTokenReference)
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = os.Stderr
+	fmt.Fprintf(stdout, "Fetching GitHub token from 1Password: %s\n", githubTokenReference)
 
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("failed to read from 1Password: %w", err)
+	token, err := readSecret(githubTokenReference)
+	if err != nil {
+		return "", err
 	}
-
-	token := strings.TrimSpace(out.String())
 	if token == "" {
 		return "", fmt.Errorf("empty token received from 1Password")
 	}
@@ -94,30 +119,54 @@ func GetGitHubTokenFrom1Password() (string, error) {
 	return token, nil
 }
 
+// readSecret reads a 1Password reference with op read.
+// In plan mode the read is recorded and a placeholder is returned.
+func readSecret(reference string) (string, error) {
+	if planner != nil {
+		planner.record(Action{Kind: ActionSecret, Summary: "op read " + reference})
+		return "<" + reference + ">", nil
+	}
+
+	cmd := command("op", "read", reference)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = stderr
+
+	if err := run(cmd); err != nil {
+		return "", fmt.Errorf("failed to read from 1Password: %w", err)
+	}
+
+	return strings.TrimSpace(out.String()), nil
+}
+
 // AuthenticateGitHub authenticates GitHub CLI with a token
 func AuthenticateGitHub(token string) error {
-	fmt.Println("Authenticating GitHub CLI...")
+	fmt.Fprintln(stdout, "Authenticating GitHub CLI...")
 
 	// Use gh auth login with token via stdin
-	cmd := exec.Command("gh", "auth", "login", "--with-token")
+	cmd := command("gh", "auth", "login", "--with-token")
 	cmd.Stdin = strings.NewReader(token)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
 
-	if err := cmd.Run(); err != nil {
+	if err := runCommand(cmd); err != nil {
 		return fmt.Errorf("failed to authenticate GitHub: %w", err)
 	}
+	rememberLogin(state.LoginGitHub, "github.com")
 
 	// Configure git to use gh as credential helper
-	gitCmd := exec.Command("gh", "auth", "setup-git")
-	gitCmd.Stdout = os.Stdout
-	gitCmd.Stderr = os.Stderr
-
-	if err := gitCmd.Run(); err != nil {
+	gitCmd := command("gh", "auth", "setup-git")
+	gitCmd.Stdout = stdout
+	gitCmd.Stderr = stderr
+
+	err := trackFile(globalGitConfig(), func() error {
+		return runCommand(gitCmd)
+	})
+	if err != nil {
 		return fmt.Errorf("failed to setup git authentication: %w", err)
 	}
 
-	fmt.Println("GitHub authentication successful!")
+	fmt.Fprintln(stdout, "GitHub authentication successful!")
 	return nil
 }
 
@@ -130,41 +179,66 @@ func InitChezmoi(username string) error {
 		}
 	}
 
-	fmt.Printf("Initializing chezmoi with GitHub user: %s\n", username)
+	fmt.Fprintf(stdout, "Initializing chezmoi with GitHub user: %s\n", username)
+
+	args := []string{"init", "--apply", username}
+	if nonInteractive {
+		// Answer template prompts with their defaults instead of waiting on a terminal
+		args = append(args, "--no-tty", "--promptDefaults")
+	}
 
-	cmd := exec.Command("chezmoi", "init", "--apply", username)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd := command("chezmoi", args...)
+	var err error
+	if nonInteractive {
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+		err = runCommand(cmd)
+	} else {
+		// Templates may prompt for values
+		err = runInteractive(cmd, "")
+	}
 
-	if err := cmd.Run(); err != nil {
+	if err != nil {
 		return fmt.Errorf("failed to initialize chezmoi: %w", err)
 	}
 
-	fmt.Println("Chezmoi initialized successfully!")
+	fmt.Fprintln(stdout, "Chezmoi initialized successfully!")
 	return nil
 }
 
 // setupChezmoiConfig creates chezmoi config with 1Password service mode
 func setupChezmoiConfig() error {
 	home := os.Getenv("HOME")
-	configDir := home + "/.config/chezmoi"
-	configFile := configDir + "/chezmoi.toml"
-
-	// Create config directory if it doesn't exist
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return fmt.Errorf("failed to create config directory: %w", err)
-	}
+	configFile := home + "/.config/chezmoi/chezmoi.toml"
 
 	// Create/overwrite config file with 1Password service mode
 	config := `[onepassword]
 mode = "service"
 `
 
-	if err := os.WriteFile(configFile, []byte(config), 0644); err != nil {
+	err := trackFile(configFile, func() error {
+		return writeFile(configFile, []byte(config), 0644)
+	})
+	if err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
-	fmt.Printf("✓ Created chezmoi config with 1Password service mode\n")
+	fmt.Fprintf(stdout, "✓ Created chezmoi config with 1Password service mode\n")
 	return nil
 }
+
+// Is1PasswordSignedIn checks whether op can already access an account
+func Is1PasswordSignedIn() bool {
+	if !IsCommandAvailable("op") {
+		return false
+	}
+	return run(command("op", "whoami")) == nil
+}
+
+// IsGitHubAuthenticated checks whether gh already has a valid login
+func IsGitHubAuthenticated() bool {
+	if !IsCommandAvailable("gh") {
+		return false
+	}
+	return run(command("gh", "auth", "status")) == nil
+}
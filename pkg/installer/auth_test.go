@@ -0,0 +1,85 @@
+package installer_test
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/whexy/wenxuan-dev-init/pkg/installer"
+	"github.com/whexy/wenxuan-dev-init/pkg/ui"
+)
+
+// typeInput makes os.Stdin read input for the rest of the test
+func typeInput(t *testing.T, input string) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteString(input)
+	w.Close()
+	stdin := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() {
+		os.Stdin = stdin
+		r.Close()
+	})
+}
+
+func TestLogin1PasswordServiceAccountPrompt(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		hookErr error
+		want    string
+		wantErr string
+	}{
+		{name: "token", input: "ops_abc\n", want: "ops_abc"},
+		{name: "empty line", input: "\n", wantErr: "no service account token provided"},
+		{name: "closed input", input: "", wantErr: "failed to read service account token: EOF"},
+		{name: "terminal unavailable", input: "ops_abc\n", hookErr: errors.New("terminal is busy"), wantErr: "terminal is busy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newFakes(t)
+			t.Setenv("OP_SERVICE_ACCOUNT_TOKEN", "")
+			installer.SetUseServiceAccount(true)
+			t.Cleanup(func() { installer.SetUseServiceAccount(false) })
+			typeInput(t, tt.input)
+			// Hide the prompt, which goes straight to the terminal
+			devNull, err := os.Open(os.DevNull)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stdout := os.Stdout
+			os.Stdout = devNull
+			t.Cleanup(func() {
+				os.Stdout = stdout
+				devNull.Close()
+			})
+			if tt.hookErr != nil {
+				ui.SetTerminalHook(func(fn func() error) error { return tt.hookErr })
+				t.Cleanup(func() { ui.SetTerminalHook(nil) })
+			}
+
+			err = installer.Login1Password()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Login1Password() error = %v, want %q", err, tt.wantErr)
+				}
+				if token := os.Getenv("OP_SERVICE_ACCOUNT_TOKEN"); token != "" {
+					t.Errorf("OP_SERVICE_ACCOUNT_TOKEN = %q after a failed prompt", token)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if token := os.Getenv("OP_SERVICE_ACCOUNT_TOKEN"); token != tt.want {
+				t.Errorf("OP_SERVICE_ACCOUNT_TOKEN = %q, want %q", token, tt.want)
+			}
+		})
+	}
+}
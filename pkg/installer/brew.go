@@ -1,14 +1,35 @@
 package installer
 
 import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"os/exec"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
 )
 
-type BrewManager struct{}
+const homebrewInstallerURL = "https://raw.githubusercontent.com/Homebrew/install/HEAD/install.sh"
+
+// ErrHomebrewMissing is returned when Homebrew is the package manager to use but it is not installed
+var ErrHomebrewMissing = errors.New("Homebrew is not installed")
+
+type BrewManager struct {
+	// path is the brew executable, which on Linux is often not on PATH yet
+	path string
+}
 
 func NewBrewManager() *BrewManager {
-	return &BrewManager{}
+	path := findBrew()
+	if path == "" && IsCommandAvailable("brew") {
+		// Installed earlier in the plan
+		path = "brew"
+	}
+	return &BrewManager{path: path}
 }
 
 func (b *BrewManager) Name() string {
@@ -16,15 +37,289 @@ func (b *BrewManager) Name() string {
 }
 
 func (b *BrewManager) IsAvailable() bool {
-	return IsCommandAvailable("brew")
+	return b.path != ""
+}
+
+// brewPrefixes are where Homebrew installs itself on macOS and Linux
+var brewPrefixes = []string{
+	"/opt/homebrew",
+	"/usr/local",
+	"/home/linuxbrew/.linuxbrew",
+}
+
+// findBrew returns the brew executable from PATH or a standard prefix, or "" when Homebrew is not installed
+func findBrew() string {
+	if path, err := lookPath("brew"); err == nil {
+		return path
+	}
+
+	prefixes := brewPrefixes
+	if home, err := os.UserHomeDir(); err == nil {
+		prefixes = append(prefixes, filepath.Join(home, ".linuxbrew"))
+	}
+	for _, prefix := range prefixes {
+		path := filepath.Join(prefix, "bin", "brew")
+		if info, err := files.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
 }
 
 func (b *BrewManager) Install(packages ...string) error {
-	args := append([]string{"install"}, packages...)
-	cmd := exec.Command("brew", args...)
-	cmd.Stdout = nil
-	cmd.Stderr = nil
+	if !b.IsAvailable() {
+		return fmt.Errorf("brew not found")
+	}
+	if err := activateHomebrew(b.path); err != nil {
+		return err
+	}
+
+	native, other := resolveAll("brew", packages)
+
+	// Batch formulae and casks, tapping what's needed first
+	var taps, formulae, casks, unsupported []string
+	for _, r := range native {
+		if r.Cask && runtime.GOOS != "darwin" {
+			unsupported = append(unsupported, r.tool)
+			continue
+		}
+
+		name := r.Name
+		if r.Tap != "" {
+			if !slices.Contains(taps, r.Tap) {
+				taps = append(taps, r.Tap)
+			}
+			name = r.Tap + "/" + r.Name
+		}
+		if r.Cask {
+			casks = append(casks, name)
+		} else {
+			formulae = append(formulae, name)
+		}
+	}
+
+	for _, tap := range taps {
+		if err := b.run("tap", tap); err != nil {
+			return fmt.Errorf("failed to tap %s: %w", tap, err)
+		}
+	}
+	if len(formulae) > 0 {
+		if err := b.run(append([]string{"install", "--formula"}, formulae...)...); err != nil {
+			return fmt.Errorf("failed to install %v: %w", formulae, err)
+		}
+	}
+	if len(casks) > 0 {
+		if err := b.run(append([]string{"install", "--cask"}, casks...)...); err != nil {
+			return fmt.Errorf("failed to install %v: %w", casks, err)
+		}
+	}
+
+	for _, r := range native {
+		if r.Service == "" {
+			continue
+		}
+		if err := b.startService(r); err != nil {
+			return fmt.Errorf("failed to start %s: %w", r.Service, err)
+		}
+	}
+
+	if err := installOther(other); err != nil {
+		return err
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("%s: Homebrew casks are only supported on macOS; install with the system package manager", strings.Join(unsupported, ", "))
+	}
+	return nil
+}
+
+func (b *BrewManager) run(args ...string) error {
+	cmd := command(b.path, args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	fmt.Fprintf(stdout, "Running: brew %v\n", args)
+	return runCommand(cmd)
+}
+
+// startService starts a recipe's service with brew services, which runs as the user like every other brew command.
+// Services that need root, such as tailscaled, are started with sudo; on Linux brew then needs --sudo-service-user.
+func (b *BrewManager) startService(r recipe) error {
+	cmd := command(b.path, "services", "start", r.Service)
+	if r.Root {
+		args := []string{"services", "start"}
+		if runtime.GOOS == "linux" {
+			name, err := invokingUser()
+			if err != nil {
+				return err
+			}
+			args = append(args, "--sudo-service-user", name)
+		}
+		cmd = sudoCommand(b.path, append(args, r.Service)...)
+	}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return runCommand(cmd)
+}
+
+// activateHomebrew puts brew and the tools it installs on PATH when brew was found in a
+// standard prefix, as `eval "$(brew shellenv)"` would in a login shell
+func activateHomebrew(brew string) error {
+	if _, err := lookPath("brew"); err == nil || planner != nil {
+		return nil
+	}
+
+	prefix := filepath.Dir(filepath.Dir(brew))
+	fmt.Fprintf(stdout, "Adding %s to PATH...\n", prefix)
+	os.Setenv("HOMEBREW_PREFIX", prefix)
+	return os.Setenv("PATH", filepath.Join(prefix, "bin")+string(os.PathListSeparator)+filepath.Join(prefix, "sbin")+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// FetchHomebrewInstaller downloads the official Homebrew installer to a temporary file so it can be
+// reviewed before it runs, returning its path and SHA-256. In plan mode nothing is downloaded.
+func FetchHomebrewInstaller() (path, sum string, err error) {
+	if isRoot() {
+		return "", "", fmt.Errorf("Homebrew cannot be installed as root; run as a regular user with sudo access")
+	}
+
+	fmt.Fprintln(stdout, "Downloading the Homebrew installer...")
+	if planner != nil {
+		planner.record(Action{Kind: ActionDownload, Summary: "Download " + homebrewInstallerURL})
+		return filepath.Join(os.TempDir(), "homebrew-install.sh"), "", nil
+	}
+
+	script, err := httpGet(homebrewInstallerURL)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to download Homebrew installer: %w", err)
+	}
+
+	f, err := os.CreateTemp("", "homebrew-install-*.sh")
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+	if _, err := f.Write(script); err != nil {
+		os.Remove(f.Name())
+		return "", "", err
+	}
+
+	digest := sha256.Sum256(script)
+	return f.Name(), hex.EncodeToString(digest[:]), nil
+}
+
+// ShowFile opens path in $PAGER (less by default) on the terminal, or prints it when no pager is installed
+func ShowFile(path string) error {
+	pager := strings.Fields(cmp.Or(os.Getenv("PAGER"), "less"))
+	if !IsCommandAvailable(pager[0]) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		_, err = stdout.Write(data)
+		return err
+	}
+	return runInteractive(command(pager[0], append(pager[1:], path)...), "")
+}
+
+// InstallHomebrew runs the installer fetched by FetchHomebrewInstaller unattended and puts brew on PATH
+func InstallHomebrew(script string) error {
+	fmt.Fprintln(stdout, "Installing Homebrew...")
+
+	// The unattended installer cannot ask for a password, so sudo credentials are cached first
+	if !nonInteractive {
+		if err := runInteractive(command("sudo", "-v"), "The Homebrew installer needs sudo; enter your password if asked."); err != nil {
+			return fmt.Errorf("failed to get sudo access: %w", err)
+		}
+	}
+
+	cmd := command("bash", script)
+	cmd.Env = []string{"NONINTERACTIVE=1"}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := runAction(Action{Kind: ActionCommand, Summary: "NONINTERACTIVE=1 bash " + script}, cmd); err != nil {
+		return fmt.Errorf("Homebrew installation failed: %w", err)
+	}
+
+	if planner != nil {
+		planner.provide("brew")
+		return nil
+	}
+	brew := findBrew()
+	if brew == "" {
+		return fmt.Errorf("brew not found after installation")
+	}
+	return activateHomebrew(brew)
+}
+
+// brewKindFlag returns the flag that tells brew whether a recipe is a formula or a cask
+func brewKindFlag(r recipe) string {
+	if r.Cask {
+		return "--cask"
+	}
+	return "--formula"
+}
+
+func (b *BrewManager) IsInstalled(pkg string) bool {
+	_, err := b.Version(pkg)
+	return err == nil
+}
+
+func (b *BrewManager) Version(pkg string) (string, error) {
+	r := resolve("brew", pkg)
+	if r.Method != methodPackage {
+		return otherVersion(r)
+	}
+	if !b.IsAvailable() {
+		return "", fmt.Errorf("brew not found")
+	}
+
+	// brew list --versions prints "name version..." for installed packages and nothing otherwise
+	out, err := queryOutput(b.path, "list", "--versions", brewKindFlag(r), r.Name)
+	fields := strings.Fields(out)
+	if err != nil || len(fields) < 2 {
+		return "", fmt.Errorf("%s: %w", pkg, ErrNotInstalled)
+	}
+	return fields[len(fields)-1], nil
+}
+
+func (b *BrewManager) Upgrade(packages ...string) error {
+	native, other := resolveAll("brew", packages)
+	if err := b.each("upgrade", native); err != nil {
+		return err
+	}
+	return installOther(other)
+}
+
+func (b *BrewManager) Remove(packages ...string) error {
+	native, other := resolveAll("brew", packages)
+	if err := b.each("uninstall", native); err != nil {
+		return err
+	}
+	return removeOther(other)
+}
+
+// each runs a brew command on the recipes, batched into formulae and casks
+func (b *BrewManager) each(command string, recipes []recipe) error {
+	if len(recipes) == 0 {
+		return nil
+	}
+	if !b.IsAvailable() {
+		return fmt.Errorf("brew not found")
+	}
+	if err := activateHomebrew(b.path); err != nil {
+		return err
+	}
 
-	fmt.Printf("Running: brew %v\n", args)
-	return cmd.Run()
+	batches := make(map[string][]string)
+	for _, r := range recipes {
+		flag := brewKindFlag(r)
+		batches[flag] = append(batches[flag], r.Name)
+	}
+	for _, flag := range []string{"--formula", "--cask"} {
+		if names := batches[flag]; len(names) > 0 {
+			if err := b.run(append([]string{command, flag}, names...)...); err != nil {
+				return fmt.Errorf("failed to %s %v: %w", command, names, err)
+			}
+		}
+	}
+	return nil
 }
@@ -0,0 +1,56 @@
+package installer_test
+
+import (
+	"os"
+	"os/user"
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/whexy/wenxuan-dev-init/pkg/installer"
+)
+
+func TestBrewInstallStartsRootServiceWithSudo(t *testing.T) {
+	f := newFakes(t, "brew")
+	name := "wx"
+	if os.Geteuid() == 0 {
+		t.Setenv("SUDO_USER", name)
+	} else {
+		u, err := user.Current()
+		if err != nil {
+			t.Fatal(err)
+		}
+		name = u.Username
+	}
+
+	if err := installer.NewBrewManager().Install("git", "tailscale"); err != nil {
+		t.Fatal(err)
+	}
+
+	// brew itself runs as the user; only tailscaled, which manages the network, is started as root
+	want := []string{
+		"/usr/bin/brew install --formula git tailscale",
+		"sudo /usr/bin/brew services start --sudo-service-user " + name + " tailscale",
+	}
+	if runtime.GOOS == "darwin" {
+		want[1] = "sudo /usr/bin/brew services start tailscale"
+	}
+	if got := f.runner.Commands(); !slices.Equal(got, want) {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+}
+
+func TestBrewInstallRootServiceNeedsUser(t *testing.T) {
+	if runtime.GOOS != "linux" || os.Geteuid() != 0 {
+		t.Skip("only root on Linux has to name the user the service runs as")
+	}
+	f := newFakes(t, "brew")
+	t.Setenv("SUDO_USER", "")
+
+	if err := installer.NewBrewManager().Install("tailscale"); err == nil {
+		t.Fatal("Install() as root without SUDO_USER succeeded")
+	}
+	if got := f.runner.Commands(); len(got) != 1 {
+		t.Errorf("commands = %q, want only the install", got)
+	}
+}
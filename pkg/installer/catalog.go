@@ -0,0 +1,351 @@
+package installer
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed catalog.yaml
+var catalogData []byte
+
+// Install methods a recipe can use instead of the package manager
+const (
+	methodPackage  = "package"
+	methodScript   = "script"
+	methodAUR      = "aur"
+	methodOPBinary = "op-binary"
+)
+
+// catalog describes how every package manager installs the tools, see catalog.yaml
+type catalog struct {
+	Tools map[string]tool `yaml:"tools"`
+	Repos struct {
+		Apt    map[string]aptRepo    `yaml:"apt"`
+		RPM    map[string]rpmRepo    `yaml:"rpm"`
+		Zypper map[string]zypperRepo `yaml:"zypper"`
+	} `yaml:"repos"`
+}
+
+// tool is a logical tool and the recipes for installing it
+type tool struct {
+	Binary   string            `yaml:"binary"`
+	Fallback string            `yaml:"fallback"`
+	Packages map[string]recipe `yaml:"packages"`
+	Script   *scriptRecipe     `yaml:"script"`
+	AUR      *aurPackage       `yaml:"aur"`
+}
+
+// recipe is how one package manager installs a tool
+type recipe struct {
+	// tool is the catalog name the recipe was resolved for
+	tool string
+
+	Name      string `yaml:"name"`
+	Repo      string `yaml:"repo"`
+	Method    string `yaml:"method"`
+	Community bool   `yaml:"community"`
+	Cask      bool   `yaml:"cask"`
+	Tap       string `yaml:"tap"`
+	Service   string `yaml:"service"`
+	// Root services are started as root; only Homebrew starts services as the user otherwise
+	Root bool `yaml:"root"`
+}
+
+// scriptRecipe is a vendor install script
+type scriptRecipe struct {
+	URL  string   `yaml:"url"`
+	Args []string `yaml:"args"`
+	// Path is where the script installs the binary
+	Path string `yaml:"path"`
+}
+
+var tools = mustLoadCatalog()
+
+// mustLoadCatalog parses the embedded catalog; it is part of the program, so errors are bugs
+func mustLoadCatalog() *catalog {
+	c := &catalog{}
+	dec := yaml.NewDecoder(bytes.NewReader(catalogData))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil {
+		panic(fmt.Sprintf("invalid tool catalog: %v", err))
+	}
+	if err := c.validate(); err != nil {
+		panic(fmt.Sprintf("invalid tool catalog: %v", err))
+	}
+	return c
+}
+
+// validate checks that methods exist and repositories are defined for the manager that references them
+func (c *catalog) validate() error {
+	for alias, repo := range c.Repos.Zypper {
+		if repo.Key == "" {
+			return fmt.Errorf("zypper repository %s: no key; refreshes do not import keys", alias)
+		}
+	}
+	for name, t := range c.Tools {
+		if err := t.checkMethod(name, t.Fallback); err != nil {
+			return err
+		}
+		for manager, r := range t.Packages {
+			if err := t.checkMethod(name, r.Method); err != nil {
+				return err
+			}
+			if r.Repo == "" {
+				continue
+			}
+			var ok bool
+			switch manager {
+			case "apt":
+				_, ok = c.Repos.Apt[r.Repo]
+			case "dnf", "yum":
+				_, ok = c.Repos.RPM[r.Repo]
+			case "zypper":
+				_, ok = c.Repos.Zypper[r.Repo]
+			}
+			if !ok {
+				return fmt.Errorf("%s: no %s repository %q", name, manager, r.Repo)
+			}
+		}
+	}
+	return nil
+}
+
+func (t tool) checkMethod(name, method string) error {
+	switch method {
+	case "", methodPackage, methodOPBinary:
+	case methodScript:
+		if t.Script == nil {
+			return fmt.Errorf("%s: script method without a script", name)
+		}
+	case methodAUR:
+		if t.AUR == nil {
+			return fmt.Errorf("%s: aur method without aur", name)
+		}
+	default:
+		return fmt.Errorf("%s: unknown method %q", name, method)
+	}
+	return nil
+}
+
+// resolve returns how manager installs pkg
+func resolve(manager, pkg string) recipe {
+	r := recipe{Name: pkg, Method: methodPackage}
+	if t, ok := tools.Tools[pkg]; ok {
+		found, ok := t.Packages[manager]
+		// Casks only exist on macOS; Linuxbrew installs those tools the fallback way
+		if ok && found.Cask && manager == "brew" && runtime.GOOS != "darwin" && t.Fallback != "" {
+			ok = false
+		}
+		if ok {
+			r = found
+		} else if t.Fallback != "" {
+			r.Method = t.Fallback
+		}
+	}
+
+	r.tool = pkg
+	if r.Name == "" {
+		r.Name = pkg
+	}
+	if r.Method == "" {
+		r.Method = methodPackage
+	}
+	return r
+}
+
+// resolveAll splits packages into the recipes the manager installs itself and those installed another way
+func resolveAll(manager string, packages []string) (native, other []recipe) {
+	for _, pkg := range packages {
+		if r := resolve(manager, pkg); r.Method == methodPackage {
+			native = append(native, r)
+		} else {
+			other = append(other, r)
+		}
+	}
+	return native, other
+}
+
+// recipeNames returns the package names of recipes
+func recipeNames(recipes []recipe) []string {
+	names := make([]string, len(recipes))
+	for i, r := range recipes {
+		names[i] = r.Name
+	}
+	return names
+}
+
+// recipeRepos returns the repositories recipes need, once each
+func recipeRepos(recipes []recipe) []string {
+	var repos []string
+	seen := make(map[string]bool)
+	for _, r := range recipes {
+		if r.Repo != "" && !seen[r.Repo] {
+			seen[r.Repo] = true
+			repos = append(repos, r.Repo)
+		}
+	}
+	return repos
+}
+
+// recipeServices returns the services recipes start after installing
+func recipeServices(recipes []recipe) []string {
+	var services []string
+	for _, r := range recipes {
+		if r.Service != "" {
+			services = append(services, r.Service)
+		}
+	}
+	return services
+}
+
+// installOther installs recipes that don't use the package manager
+func installOther(recipes []recipe) error {
+	for _, r := range recipes {
+		var err error
+		switch r.Method {
+		case methodScript:
+			fmt.Fprintf(stdout, "\n📦 Installing %s (using official installer)...\n", r.tool)
+			err = runInstallScript(r.tool, tools.Tools[r.tool].Script)
+		case methodAUR:
+			fmt.Fprintf(stdout, "\n📦 Installing %s from the AUR...\n", r.tool)
+			err = installAURPackage(r.Name, *tools.Tools[r.tool].AUR)
+		case methodOPBinary:
+			fmt.Fprintln(stdout, "\n📦 Installing 1Password CLI (static binary)...")
+			err = Install1PasswordCLIBinary()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to install %s: %w", r.tool, err)
+		}
+	}
+	return nil
+}
+
+// otherPath returns where a recipe that doesn't use the package manager puts the binary
+func otherPath(r recipe) string {
+	switch r.Method {
+	case methodScript:
+		return tools.Tools[r.tool].Script.Path
+	case methodOPBinary:
+		return opBinaryPath
+	}
+	return ""
+}
+
+// otherInstalled reports whether the binary of a recipe that doesn't use the package manager exists
+func otherInstalled(r recipe) bool {
+	path := otherPath(r)
+	if path == "" {
+		return false
+	}
+	_, err := files.Stat(path)
+	return err == nil
+}
+
+// otherVersion returns the version the binary of a recipe that doesn't use the package manager reports
+func otherVersion(r recipe) (string, error) {
+	if !otherInstalled(r) {
+		return "", fmt.Errorf("%s: %w", r.tool, ErrNotInstalled)
+	}
+	return binaryVersion(otherPath(r))
+}
+
+// removeOther deletes the binaries of recipes that don't use the package manager
+func removeOther(recipes []recipe) error {
+	for _, r := range recipes {
+		path := otherPath(r)
+		if path == "" || !otherInstalled(r) {
+			continue
+		}
+		cmd := sudoCommand("rm", "-f", path)
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+		if err := runAction(Action{Kind: ActionFile, Summary: "Remove " + r.tool, Path: path}, cmd); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", r.tool, err)
+		}
+	}
+	return nil
+}
+
+// versionPattern matches version numbers such as 2.30.0 or v2.47.1 in --version output
+var versionPattern = regexp.MustCompile(`v?\d+(\.\d+)+`)
+
+// binaryVersion runs `binary --version` and returns the first version number it prints
+func binaryVersion(binary string) (string, error) {
+	out, err := queryOutput(binary, "--version")
+	if err != nil {
+		return "", err
+	}
+	version := versionPattern.FindString(out)
+	if version == "" {
+		return "", fmt.Errorf("no version in %q", out)
+	}
+	return strings.TrimPrefix(version, "v"), nil
+}
+
+// ToolBinary returns the command a tool provides; packages not in the catalog are assumed to provide their own name
+func ToolBinary(name string) string {
+	if t, ok := tools.Tools[name]; ok && t.Binary != "" {
+		return t.Binary
+	}
+	return name
+}
+
+// runInstallScript downloads a vendor install script and runs it with sh as root
+func runInstallScript(name string, s *scriptRecipe) error {
+	var script []byte
+	if planner == nil {
+		var err error
+		if script, err = httpGet(s.URL); err != nil {
+			return err
+		}
+	}
+
+	cmd := sudoCommand("sh", append([]string{"-s", "--"}, s.Args...)...)
+	cmd.Stdin = bytes.NewReader(script)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := runAction(Action{Kind: ActionDownload, Summary: fmt.Sprintf("Run the %s installer from %s", name, s.URL), Path: s.Path}, cmd); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "✓ %s installed successfully\n", name)
+	return nil
+}
+
+// repoVars returns the values of the repository URL placeholders on this system
+func repoVars() *strings.Replacer {
+	release := osRelease()
+
+	debArch, rpmArch := runtime.GOARCH, runtime.GOARCH
+	switch runtime.GOARCH {
+	case "amd64":
+		rpmArch = "x86_64"
+	case "arm64":
+		rpmArch = "aarch64"
+	case "arm":
+		debArch, rpmArch = "armhf", "armv7hl"
+	case "386":
+		debArch, rpmArch = "i386", "i686"
+	}
+
+	rpmDistro := "fedora"
+	if release["ID"] != "fedora" {
+		// RHEL, CentOS Stream, Rocky and Alma share the rhel/<major> repositories
+		major, _, _ := strings.Cut(release["VERSION_ID"], ".")
+		rpmDistro = "rhel/" + major
+	}
+
+	return strings.NewReplacer(
+		"{arch}", debArch,
+		"{rpm_arch}", rpmArch,
+		"{rpm_distro}", rpmDistro,
+		"{id}", release["ID"],
+		"{codename}", release["VERSION_CODENAME"],
+	)
+}
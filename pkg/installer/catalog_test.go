@@ -0,0 +1,22 @@
+package installer
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestResolveBrewCaskOnLinux(t *testing.T) {
+	r := resolve("brew", "1password-cli")
+	want := recipe{tool: "1password-cli", Name: "1password-cli", Method: methodOPBinary}
+	if runtime.GOOS == "darwin" {
+		want = recipe{tool: "1password-cli", Name: "1password-cli", Method: methodPackage, Cask: true, Tap: "1password/tap"}
+	}
+	if r != want {
+		t.Errorf("resolve(brew, 1password-cli) = %+v, want %+v", r, want)
+	}
+
+	// Formulae are unaffected
+	if r := resolve("brew", "tailscale"); r.Method != methodPackage || r.Cask {
+		t.Errorf("resolve(brew, tailscale) = %+v, want the formula", r)
+	}
+}
@@ -1,11 +1,11 @@
 package installer
 
 import (
+	"bytes"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"strings"
 )
 
 type DevboxManager struct{}
@@ -23,76 +23,196 @@ func (d *DevboxManager) IsAvailable() bool {
 }
 
 func (d *DevboxManager) Install(packages ...string) error {
-	args := append([]string{"global", "add"}, packages...)
-	cmd := exec.Command("devbox", args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	native, other := resolveAll("devbox", packages)
+
+	if len(native) > 0 {
+		names := recipeNames(native)
+		args := append([]string{"global", "add"}, names...)
+		cmd := command("devbox", args...)
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+
+		fmt.Fprintf(stdout, "Running: devbox global add %v\n", names)
+		if err := runCommand(cmd); err != nil {
+			return err
+		}
+	}
 
-	fmt.Printf("Running: devbox global add %v\n", packages)
-	return cmd.Run()
+	return installOther(other)
 }
 
 // InstallDevbox installs devbox on the system
 func InstallDevbox() error {
-	fmt.Println("Installing devbox...")
+	fmt.Fprintln(stdout, "Installing devbox...")
+
+	if planner != nil {
+		planner.record(Action{Kind: ActionDownload, Summary: "Download https://get.jetify.com/devbox and run it with bash"})
+		planner.provide("devbox")
+		return nil
+	}
 
-	// Download the installation script using Go's HTTP client
-	resp, err := http.Get("https://get.jetify.com/devbox")
+	// Download the installation script
+	script, err := httpGet("https://get.jetify.com/devbox")
 	if err != nil {
 		return fmt.Errorf("failed to download devbox installer: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to download devbox installer: HTTP %d", resp.StatusCode)
+	// Execute the script with bash, which reads it from stdin
+	cmd := command("bash", "-s")
+	cmd.Stdin = bytes.NewReader(script)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	// Wait for the command to complete
+	if err := run(cmd); err != nil {
+		return fmt.Errorf("devbox installation failed: %w", err)
+	}
+
+	return nil
+}
+
+// InitDevboxShell loads the environment from `devbox global shellenv` into this process,
+// so devbox and the packages it installs can be used without reloading the shell
+func InitDevboxShell() error {
+	fmt.Fprintln(stdout, "Initializing devbox shell environment...")
+
+	cmd := command("devbox", "global", "shellenv")
+	if planner != nil {
+		planner.record(Action{Kind: ActionCommand, Summary: "devbox global shellenv (applied to this process)", Command: cmd.Args})
+		return nil
+	}
+
+	cmd.Stderr = stderr
+	env, err := output(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to get devbox shellenv: %w", err)
 	}
 
-	// Read the script content
-	script, err := io.ReadAll(resp.Body)
+	changes, err := parseShellenv(string(env), os.LookupEnv)
+	if err != nil {
+		return fmt.Errorf("failed to parse devbox shellenv: %w", err)
+	}
+	for _, c := range changes {
+		if c.unset {
+			os.Unsetenv(c.name)
+		} else {
+			os.Setenv(c.name, c.value)
+		}
+	}
+	return nil
+}
+
+// DevboxInitHook returns the shell rc file for the user's login shell and the line that initializes devbox in it
+func DevboxInitHook() (rcFile, line string, err error) {
+	home, err := os.UserHomeDir()
 	if err != nil {
-		return fmt.Errorf("failed to read installer script: %w", err)
+		return "", "", err
 	}
 
-	// Execute the script with bash
-	cmd := exec.Command("bash", "-s")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	switch shell := filepath.Base(os.Getenv("SHELL")); shell {
+	case "bash":
+		return filepath.Join(home, ".bashrc"), `eval "$(devbox global shellenv --init-hook)"`, nil
+	case "zsh":
+		dir := os.Getenv("ZDOTDIR")
+		if dir == "" {
+			dir = home
+		}
+		return filepath.Join(dir, ".zshrc"), `eval "$(devbox global shellenv --init-hook)"`, nil
+	case "fish":
+		return filepath.Join(home, ".config", "fish", "config.fish"), "devbox global shellenv --init-hook | source", nil
+	default:
+		return "", "", fmt.Errorf("unsupported shell %q", shell)
+	}
+}
+
+// HasDevboxInitHook reports whether rcFile already initializes devbox
+func HasDevboxInitHook(rcFile string) bool {
+	data, err := files.ReadFile(rcFile)
+	return err == nil && strings.Contains(string(data), "devbox global shellenv")
+}
+
+// AddDevboxInitHook appends the devbox init hook to rcFile
+func AddDevboxInitHook(rcFile, line string) error {
+	return trackAppend(rcFile, "\n# Added by wenxuan-dev-init\n"+line+"\n", 0644)
+}
 
-	// Provide the script content as stdin
-	stdin, err := cmd.StdinPipe()
+// globalPackages returns the installed version of each package in the devbox global profile
+func (d *DevboxManager) globalPackages() map[string]string {
+	packages := make(map[string]string)
+	out, err := queryOutput("devbox", "global", "list")
 	if err != nil {
-		return fmt.Errorf("failed to create stdin pipe: %w", err)
+		return packages
 	}
 
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start bash: %w", err)
+	// Entries look like "* gh@latest - 2.62.0"; older devbox versions omit the resolved version
+	for _, line := range strings.Split(out, "\n") {
+		entry, ok := strings.CutPrefix(strings.TrimSpace(line), "* ")
+		if !ok {
+			continue
+		}
+		spec, resolved, hasResolved := strings.Cut(entry, " - ")
+		name, version, _ := strings.Cut(strings.TrimSpace(spec), "@")
+		if hasResolved {
+			version = strings.TrimSpace(resolved)
+		}
+		packages[name] = version
 	}
+	return packages
+}
 
-	// Write the script to stdin
-	if _, err := stdin.Write(script); err != nil {
-		return fmt.Errorf("failed to write script to bash: %w", err)
+func (d *DevboxManager) IsInstalled(pkg string) bool {
+	r := resolve("devbox", pkg)
+	if r.Method != methodPackage {
+		return otherInstalled(r)
 	}
-	stdin.Close()
+	_, ok := d.globalPackages()[r.Name]
+	return ok
+}
 
-	// Wait for the command to complete
-	if err := cmd.Wait(); err != nil {
-		return fmt.Errorf("devbox installation failed: %w", err)
+func (d *DevboxManager) Version(pkg string) (string, error) {
+	r := resolve("devbox", pkg)
+	if r.Method != methodPackage {
+		return otherVersion(r)
+	}
+	version, ok := d.globalPackages()[r.Name]
+	if !ok {
+		return "", fmt.Errorf("%s: %w", pkg, ErrNotInstalled)
 	}
+	return version, nil
+}
 
-	return nil
+func (d *DevboxManager) Upgrade(packages ...string) error {
+	native, other := resolveAll("devbox", packages)
+
+	if len(native) > 0 {
+		names := recipeNames(native)
+		cmd := command("devbox", append([]string{"global", "update"}, names...)...)
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+
+		fmt.Fprintf(stdout, "Running: devbox global update %v\n", names)
+		if err := runCommand(cmd); err != nil {
+			return fmt.Errorf("failed to upgrade %v: %w", names, err)
+		}
+	}
+
+	return installOther(other)
 }
 
-// InitDevboxShell initializes devbox shell environment
-func InitDevboxShell() error {
-	fmt.Println("Initializing devbox shell environment...")
-	// This prints the shell initialization commands
-	cmd := exec.Command("devbox", "global", "shellenv", "--init-hook")
-	output, err := cmd.Output()
-	if err != nil {
-		return err
+func (d *DevboxManager) Remove(packages ...string) error {
+	native, other := resolveAll("devbox", packages)
+
+	if len(native) > 0 {
+		names := recipeNames(native)
+		cmd := command("devbox", append([]string{"global", "rm"}, names...)...)
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+
+		fmt.Fprintf(stdout, "Running: devbox global rm %v\n", names)
+		if err := runCommand(cmd); err != nil {
+			return fmt.Errorf("failed to remove %v: %w", names, err)
+		}
 	}
 
-	fmt.Println("Add the following to your shell RC file:")
-	fmt.Println(string(output))
-	return nil
+	return removeOther(other)
 }
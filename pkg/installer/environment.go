@@ -1,19 +1,21 @@
 package installer
 
 import (
+	"fmt"
 	"os"
+	"os/user"
 	"strings"
 )
 
 // IsRunningInContainer detects if the program is running inside a container
 func IsRunningInContainer() bool {
 	// Check for .dockerenv file
-	if _, err := os.Stat("/.dockerenv"); err == nil {
+	if _, err := files.Stat("/.dockerenv"); err == nil {
 		return true
 	}
 
 	// Check cgroup to see if we're in a container
-	if data, err := os.ReadFile("/proc/1/cgroup"); err == nil {
+	if data, err := files.ReadFile("/proc/1/cgroup"); err == nil {
 		content := string(data)
 		if strings.Contains(content, "docker") ||
 			strings.Contains(content, "lxc") ||
@@ -37,3 +39,41 @@ func IsRunningInContainer() bool {
 
 	return false
 }
+
+// isRoot reports whether the process runs as root, in which case sudo is not needed
+func isRoot() bool {
+	return os.Geteuid() == 0
+}
+
+// osRelease parses /etc/os-release into its keys; it is empty when the file is missing
+func osRelease() map[string]string {
+	release := make(map[string]string)
+	data, err := files.ReadFile("/etc/os-release")
+	if err != nil {
+		return release
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok || strings.HasPrefix(key, "#") {
+			continue
+		}
+		release[key] = strings.Trim(value, `"'`)
+	}
+	return release
+}
+
+// invokingUser returns the regular user setup runs for, looking through sudo
+func invokingUser() (string, error) {
+	if isRoot() {
+		if name := os.Getenv("SUDO_USER"); name != "" && name != "root" {
+			return name, nil
+		}
+		return "", fmt.Errorf("cannot tell which user to run as; run as a regular user with sudo access")
+	}
+	u, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return u.Username, nil
+}
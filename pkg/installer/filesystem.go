@@ -0,0 +1,76 @@
+package installer
+
+import (
+	"io/fs"
+	"os"
+	"os/exec"
+)
+
+// FileSystem holds the files the installer reads and writes itself rather than through commands,
+// such as repository files it probes and shell rc files it edits. Temporary work directories are not part of it.
+type FileSystem interface {
+	Stat(name string) (fs.FileInfo, error)
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+	// AppendFile appends data to name, creating it with perm if it does not exist
+	AppendFile(name string, data []byte, perm fs.FileMode) error
+	MkdirAll(path string, perm fs.FileMode) error
+	Remove(name string) error
+}
+
+// OSFileSystem is the filesystem of this machine
+type OSFileSystem struct{}
+
+func (OSFileSystem) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (OSFileSystem) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+func (OSFileSystem) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return os.WriteFile(name, data, perm)
+}
+
+func (OSFileSystem) AppendFile(name string, data []byte, perm fs.FileMode) error {
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (OSFileSystem) MkdirAll(path string, perm fs.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+func (OSFileSystem) Remove(name string) error {
+	return os.Remove(name)
+}
+
+// files is the filesystem the installer works on
+var files FileSystem = OSFileSystem{}
+
+// SetFileSystem makes the installer read and write its files in f; nil restores OSFileSystem
+func SetFileSystem(f FileSystem) {
+	if f == nil {
+		f = OSFileSystem{}
+	}
+	files = f
+}
+
+// lookPath finds the commands the installer checks for
+var lookPath = exec.LookPath
+
+// SetLookPath makes the installer find commands with f; nil restores exec.LookPath
+func SetLookPath(f func(file string) (string, error)) {
+	if f == nil {
+		f = exec.LookPath
+	}
+	lookPath = f
+}
@@ -0,0 +1,164 @@
+package installer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// probe runs a read-only command and returns its output; a failure carries what the command printed on stderr
+func probe(name string, args ...string) (string, error) {
+	out, err := queryOutput(name, args...)
+	var cmdErr *CommandError
+	if errors.As(err, &cmdErr) && len(cmdErr.Output) > 0 {
+		return out, fmt.Errorf("%s", strings.Join(cmdErr.Output, "\n"))
+	}
+	return out, err
+}
+
+// OPAccount is the account op is signed in to
+type OPAccount struct {
+	URL   string `json:"url"`
+	Email string `json:"email"`
+	// UserType is SERVICE_ACCOUNT for service account tokens
+	UserType string `json:"user_type"`
+}
+
+// OPWhoami returns the account op is signed in to
+func OPWhoami() (OPAccount, error) {
+	var account OPAccount
+	out, err := probe("op", "whoami", "--format", "json")
+	if err != nil {
+		return account, err
+	}
+	if err := json.Unmarshal([]byte(out), &account); err != nil {
+		return account, fmt.Errorf("unexpected op whoami output: %w", err)
+	}
+	return account, nil
+}
+
+// CheckSecret reads a 1Password reference and throws the value away
+func CheckSecret(reference string) error {
+	value, err := probe("op", "read", reference)
+	if err != nil {
+		return err
+	}
+	if value == "" {
+		return fmt.Errorf("%s is empty", reference)
+	}
+	return nil
+}
+
+// GitHubAuth is the gh login for github.com
+type GitHubAuth struct {
+	Account string
+	// Scopes are the scopes of a classic token; fine-grained tokens and GitHub Apps report none
+	Scopes []string
+}
+
+var (
+	ghAccountPattern = regexp.MustCompile(`Logged in to \S+ (?:account|as) (\S+)`)
+	ghScopesPattern  = regexp.MustCompile(`Token scopes: (.*)`)
+)
+
+// GitHubAuthStatus returns the gh login for github.com
+func GitHubAuthStatus() (GitHubAuth, error) {
+	var auth GitHubAuth
+	// gh prints the status on stderr before 2.40 and on stdout since
+	out, err := combinedOutput(command("gh", "auth", "status", "--hostname", "github.com"))
+	if err != nil {
+		return auth, fmt.Errorf("%s", firstNonEmptyLine(string(out), err.Error()))
+	}
+
+	if m := ghAccountPattern.FindStringSubmatch(string(out)); m != nil {
+		auth.Account = m[1]
+	}
+	if m := ghScopesPattern.FindStringSubmatch(string(out)); m != nil {
+		for _, scope := range strings.Split(m[1], ",") {
+			if scope = strings.Trim(strings.TrimSpace(scope), "'\""); scope != "" && scope != "none" {
+				auth.Scopes = append(auth.Scopes, scope)
+			}
+		}
+	}
+	return auth, nil
+}
+
+// GitCredentialHelper returns the credential helper git uses for github.com, or "" when there is none
+func GitCredentialHelper() (string, error) {
+	out, err := queryOutput("git", "config", "--get-urlmatch", "credential.helper", "https://github.com")
+	if exitCode(err) == 1 {
+		// Exit code 1 means the key is not set
+		return "", nil
+	}
+	return out, err
+}
+
+// ChezmoiStatus returns whether chezmoi has a source directory and the targets that differ from it
+func ChezmoiStatus() (initialized bool, changed []string, err error) {
+	source, err := probe("chezmoi", "source-path")
+	if err != nil {
+		return false, nil, nil
+	}
+	if _, err := files.Stat(source); err != nil {
+		return false, nil, nil
+	}
+
+	if _, err := probe("chezmoi", "verify"); err == nil {
+		return true, nil, nil
+	}
+	out, err := probe("chezmoi", "status")
+	if err != nil {
+		return true, nil, err
+	}
+	for _, line := range strings.Split(out, "\n") {
+		// Lines are two status columns and the target path
+		if len(line) > 3 {
+			changed = append(changed, strings.TrimSpace(line[3:]))
+		}
+	}
+	return true, changed, nil
+}
+
+// TailscaleState is the state tailscaled reports
+type TailscaleState struct {
+	// BackendState is Running when connected, NeedsLogin, NeedsMachineAuth, Stopped or Starting otherwise
+	BackendState string
+	HostName     string
+	IPs          []string
+}
+
+// TailscaleStatus returns the state of tailscaled from tailscale status --json
+func TailscaleStatus() (TailscaleState, error) {
+	var st TailscaleState
+	// tailscale status exits non-zero when logged out but still prints the JSON
+	out, err := queryOutput("tailscale", "status", "--json")
+	var status struct {
+		BackendState string
+		Self         struct {
+			HostName     string
+			TailscaleIPs []string
+		}
+	}
+	if jsonErr := json.Unmarshal([]byte(out), &status); jsonErr != nil {
+		if err != nil {
+			return st, fmt.Errorf("tailscaled is not reachable: %w", err)
+		}
+		return st, fmt.Errorf("unexpected tailscale status output: %w", jsonErr)
+	}
+
+	st.BackendState = status.BackendState
+	st.HostName = status.Self.HostName
+	st.IPs = status.Self.TailscaleIPs
+	return st, nil
+}
+
+func firstNonEmptyLine(s, fallback string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return fallback
+}
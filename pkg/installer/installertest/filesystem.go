@@ -0,0 +1,75 @@
+package installertest
+
+import (
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+)
+
+// FileSystem is an installer.FileSystem that keeps every path under a root directory,
+// so /etc/apt/sources.list.d/gh.list is Root/etc/apt/sources.list.d/gh.list
+type FileSystem struct {
+	Root string
+}
+
+// NewFileSystem returns a filesystem rooted at root, usually t.TempDir()
+func NewFileSystem(root string) *FileSystem {
+	return &FileSystem{Root: root}
+}
+
+// Path returns where name is kept on the host
+func (f *FileSystem) Path(name string) string {
+	return filepath.Join(f.Root, name)
+}
+
+// Write creates name with content, along with its parent directories
+func (f *FileSystem) Write(name, content string) error {
+	if err := os.MkdirAll(filepath.Dir(f.Path(name)), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(f.Path(name), []byte(content), 0644)
+}
+
+func (f *FileSystem) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(f.Path(name))
+}
+
+func (f *FileSystem) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(f.Path(name))
+}
+
+func (f *FileSystem) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return os.WriteFile(f.Path(name), data, perm)
+}
+
+func (f *FileSystem) AppendFile(name string, data []byte, perm fs.FileMode) error {
+	file, err := os.OpenFile(f.Path(name), os.O_APPEND|os.O_CREATE|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (f *FileSystem) MkdirAll(path string, perm fs.FileMode) error {
+	return os.MkdirAll(f.Path(path), perm)
+}
+
+func (f *FileSystem) Remove(name string) error {
+	return os.Remove(f.Path(name))
+}
+
+// LookPath returns a lookup for installer.SetLookPath that finds only the named commands, in /usr/bin
+func LookPath(commands ...string) func(string) (string, error) {
+	return func(file string) (string, error) {
+		if slices.Contains(commands, file) {
+			return "/usr/bin/" + file, nil
+		}
+		return "", &exec.Error{Name: file, Err: exec.ErrNotFound}
+	}
+}
@@ -0,0 +1,48 @@
+package installertest
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Transport is an http.RoundTripper for installer.SetHTTPClient that serves registered bodies and records the URLs fetched.
+// Unregistered URLs get a 404.
+type Transport struct {
+	mu     sync.Mutex
+	bodies map[string]string
+	urls   []string
+}
+
+// NewTransport returns a transport that serves nothing
+func NewTransport() *Transport {
+	return &Transport{bodies: make(map[string]string)}
+}
+
+// Serve makes url return body
+func (t *Transport) Serve(url, body string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.bodies[url] = body
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	t.urls = append(t.urls, req.URL.String())
+	body, ok := t.bodies[req.URL.String()]
+	t.mu.Unlock()
+
+	resp := &http.Response{StatusCode: http.StatusOK, Status: "200 OK", Body: io.NopCloser(strings.NewReader(body)), Request: req}
+	if !ok {
+		resp.StatusCode, resp.Status = http.StatusNotFound, "404 Not Found"
+	}
+	return resp, nil
+}
+
+// URLs returns the URLs fetched so far, in order
+func (t *Transport) URLs() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.urls...)
+}
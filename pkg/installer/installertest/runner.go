@@ -0,0 +1,142 @@
+// Package installertest provides fakes for the installer's commands, files, command lookup and downloads,
+// so install flows can be tested without touching the host
+package installertest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/whexy/wenxuan-dev-init/pkg/installer"
+)
+
+// Call is a command the fake runner was asked to run
+type Call struct {
+	Args       []string
+	Dir        string
+	Env        []string
+	Stdin      string
+	Privileged bool
+}
+
+// String returns the command line, prefixed with sudo for privileged commands
+func (c Call) String() string {
+	line := strings.Join(c.Args, " ")
+	if c.Privileged {
+		return "sudo " + line
+	}
+	return line
+}
+
+// Response is what a faked command prints and how it exits
+type Response struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// ExitError is returned for responses with a non-zero exit code
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+func (e *ExitError) ExitCode() int {
+	return e.Code
+}
+
+// Runner records the commands it is asked to run instead of running them.
+// A command gets the response registered for the longest prefix of its command line, or succeeds silently.
+type Runner struct {
+	mu        sync.Mutex
+	calls     []Call
+	responses map[string]Response
+}
+
+// NewRunner returns a runner with no responses
+func NewRunner() *Runner {
+	return &Runner{responses: make(map[string]Response)}
+}
+
+// Respond registers the response of commands whose command line, without sudo, starts with prefix
+func (r *Runner) Respond(prefix string, resp Response) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.responses[prefix] = resp
+}
+
+// Fail makes commands starting with prefix exit with code and print message on stderr
+func (r *Runner) Fail(prefix string, code int, message string) {
+	r.Respond(prefix, Response{Stderr: message, ExitCode: code})
+}
+
+func (r *Runner) Run(ctx context.Context, cmd installer.Command) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	call := Call{Args: cmd.Args, Dir: cmd.Dir, Env: cmd.Env, Privileged: cmd.Privileged}
+	if cmd.Stdin != nil {
+		data, err := io.ReadAll(cmd.Stdin)
+		if err != nil {
+			return err
+		}
+		call.Stdin = string(data)
+	}
+
+	r.mu.Lock()
+	r.calls = append(r.calls, call)
+	resp := r.response(strings.Join(cmd.Args, " "))
+	r.mu.Unlock()
+
+	if cmd.Stdout != nil && resp.Stdout != "" {
+		io.WriteString(cmd.Stdout, resp.Stdout)
+	}
+	if cmd.Stderr != nil && resp.Stderr != "" {
+		io.WriteString(cmd.Stderr, resp.Stderr)
+	}
+	if resp.ExitCode != 0 {
+		return &ExitError{Code: resp.ExitCode}
+	}
+	return nil
+}
+
+// response returns the response with the longest prefix of line; the caller holds the lock
+func (r *Runner) response(line string) Response {
+	var best string
+	found := false
+	for prefix := range r.responses {
+		if strings.HasPrefix(line, prefix) && (!found || len(prefix) > len(best)) {
+			best, found = prefix, true
+		}
+	}
+	return r.responses[best]
+}
+
+// Calls returns the commands run so far, in order
+func (r *Runner) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Call(nil), r.calls...)
+}
+
+// Commands returns the command lines run so far, in order
+func (r *Runner) Commands() []string {
+	var lines []string
+	for _, c := range r.Calls() {
+		lines = append(lines, c.String())
+	}
+	return lines
+}
+
+// Reset forgets the commands run so far and keeps the responses
+func (r *Runner) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = nil
+}
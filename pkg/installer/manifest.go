@@ -0,0 +1,161 @@
+package installer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/whexy/wenxuan-dev-init/pkg/state"
+)
+
+// manifest records the changes setup makes; nil records nothing
+var manifest *state.Manifest
+
+// SetManifest makes the installer record the files, packages and logins it changes in m
+func SetManifest(m *state.Manifest) {
+	manifest = m
+}
+
+// remember applies change to the manifest and saves it; it is a no-op in plan mode or without a manifest
+func remember(change func(m *state.Manifest)) {
+	if manifest == nil || planner != nil {
+		return
+	}
+	change(manifest)
+	if err := manifest.Save(); err != nil {
+		fmt.Fprintf(stderr, "warning: %v\n", err)
+	}
+}
+
+// trackFile runs change and records path in the manifest as created or modified, with its new hash
+func trackFile(path string, change func() error) error {
+	_, err := files.Stat(path)
+	existed := err == nil
+	if err := change(); err != nil {
+		return err
+	}
+	if _, err := files.Stat(path); err == nil {
+		remember(func(m *state.Manifest) {
+			m.AddFile(state.File{Path: path, Created: !existed, SHA256: FileHash(path), Time: time.Now()})
+		})
+	}
+	return nil
+}
+
+// trackAppend appends text to path and records the append in the manifest
+func trackAppend(path, text string, perm os.FileMode) error {
+	_, err := files.Stat(path)
+	existed := err == nil
+	if err := appendFile(path, []byte(text), perm); err != nil {
+		return err
+	}
+	f := state.File{Path: path, Created: !existed, SHA256: FileHash(path), Time: time.Now()}
+	if existed {
+		f.Append = text
+	}
+	remember(func(m *state.Manifest) { m.AddFile(f) })
+	return nil
+}
+
+// FileHash returns the SHA-256 of a file, or "" when it cannot be read
+func FileHash(path string) string {
+	data, err := files.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// missingPackages returns the packages pkgMgr has not installed yet
+func missingPackages(pkgMgr PackageManager, packages []string) []string {
+	if manifest == nil || planner != nil {
+		return nil
+	}
+	var missing []string
+	for _, pkg := range packages {
+		if !pkgMgr.IsInstalled(pkg) {
+			missing = append(missing, pkg)
+		}
+	}
+	return missing
+}
+
+// rememberPackages records the packages pkgMgr installed, with their version and, for vendor installers, the binary
+func rememberPackages(pkgMgr PackageManager, packages []string) {
+	remember(func(m *state.Manifest) {
+		for _, pkg := range packages {
+			if !pkgMgr.IsInstalled(pkg) {
+				continue
+			}
+			version, _ := pkgMgr.Version(pkg)
+			p := state.Package{Name: pkg, Manager: pkgMgr.Name(), Version: version, Time: time.Now()}
+			if r := resolve(pkgMgr.Name(), pkg); r.Method != methodPackage {
+				p.Path = otherPath(r)
+			}
+			m.AddPackage(p)
+		}
+	})
+}
+
+// rememberLogin records an account setup signed in to
+func rememberLogin(service, account string) {
+	remember(func(m *state.Manifest) {
+		m.AddLogin(state.Login{Service: service, Account: account, Time: time.Now()})
+	})
+}
+
+// opAccounts returns the user IDs of the accounts op has been signed in to on this machine
+func opAccounts() []string {
+	out, err := queryOutput("op", "account", "list", "--format", "json")
+	if err != nil {
+		return nil
+	}
+	var accounts []struct {
+		UserUUID string `json:"user_uuid"`
+	}
+	if err := json.Unmarshal([]byte(out), &accounts); err != nil {
+		return nil
+	}
+	var ids []string
+	for _, a := range accounts {
+		ids = append(ids, a.UserUUID)
+	}
+	return ids
+}
+
+// rememberNewOPAccounts records the accounts that are not in before
+func rememberNewOPAccounts(before []string) {
+	for _, id := range opAccounts() {
+		if !slices.Contains(before, id) {
+			rememberLogin(state.Login1Password, id)
+		}
+	}
+}
+
+// globalGitConfig returns the file git config --global writes to
+func globalGitConfig() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	legacy := filepath.Join(home, ".gitconfig")
+	if _, err := os.Stat(legacy); err == nil {
+		return legacy
+	}
+
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		configHome = filepath.Join(home, ".config")
+	}
+	xdg := filepath.Join(configHome, "git", "config")
+	if _, err := os.Stat(xdg); err == nil {
+		return xdg
+	}
+	return legacy
+}
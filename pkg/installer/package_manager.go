@@ -1,18 +1,33 @@
 package installer
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
-	"os/exec"
 	"runtime"
+	"slices"
+	"strings"
 )
 
-// PackageManager defines the interface for different package managers
+// PackageManager defines the interface for different package managers.
+// Packages are catalog tool names or plain package names, as for Install.
 type PackageManager interface {
 	Name() string
 	Install(packages ...string) error
 	IsAvailable() bool
+	// IsInstalled reports whether pkg is installed through this package manager
+	IsInstalled(pkg string) bool
+	// Version returns the installed version of pkg, or ErrNotInstalled
+	Version(pkg string) (string, error)
+	// Upgrade upgrades installed packages to their latest versions
+	Upgrade(packages ...string) error
+	// Remove uninstalls packages
+	Remove(packages ...string) error
 }
 
+// ErrNotInstalled is returned for packages the package manager has not installed
+var ErrNotInstalled = errors.New("not installed")
+
 // DetectPackageManager detects the available package manager on the system
 func DetectPackageManager() (PackageManager, error) {
 	// Check for devbox first
@@ -20,32 +35,118 @@ func DetectPackageManager() (PackageManager, error) {
 		return NewDevboxManager(), nil
 	}
 
-	// Detect based on OS
+	return DetectSystemPackageManager()
+}
+
+// preferredPackageManager names the system package manager to use instead of detecting one
+var preferredPackageManager string
+
+// SetPreferredPackageManager selects the system package manager by name; "" detects it
+func SetPreferredPackageManager(name string) error {
+	if name != "" && !slices.Contains(PackageManagerNames(), name) {
+		return fmt.Errorf("unknown package manager %q (known: %s)", name, strings.Join(PackageManagerNames(), ", "))
+	}
+	preferredPackageManager = name
+	return nil
+}
+
+// PackageManagerNames lists the supported system package managers
+func PackageManagerNames() []string {
+	var names []string
+	for _, m := range allSystemPackageManagers() {
+		names = append(names, m.Name())
+	}
+	return names
+}
+
+func allSystemPackageManagers() []PackageManager {
+	return []PackageManager{
+		NewAptManager(),
+		NewPacmanManager(),
+		NewDnfManager(),
+		NewYumManager(),
+		NewZypperManager(),
+		NewApkManager(),
+		NewBrewManager(),
+	}
+}
+
+// systemPackageManagers lists the package managers to try on this OS, in order of preference
+func systemPackageManagers() []PackageManager {
 	switch runtime.GOOS {
 	case "darwin":
-		if IsCommandAvailable("brew") {
-			return NewBrewManager(), nil
-		}
+		return []PackageManager{NewBrewManager()}
 	case "linux":
-		if IsCommandAvailable("apt-get") {
-			return NewAptManager(), nil
-		}
-		if IsCommandAvailable("pacman") {
-			return NewPacmanManager(), nil
-		}
-		if IsCommandAvailable("dnf") {
-			return NewDnfManager(), nil
+		// Linuxbrew comes last so the distribution's own manager wins
+		return allSystemPackageManagers()
+	}
+	return nil
+}
+
+// DetectSystemPackageManager detects the OS package manager, ignoring devbox
+func DetectSystemPackageManager() (PackageManager, error) {
+	if preferredPackageManager != "" {
+		for _, m := range allSystemPackageManagers() {
+			if m.Name() == preferredPackageManager {
+				if !m.IsAvailable() {
+					if m.Name() == "brew" {
+						return nil, ErrHomebrewMissing
+					}
+					return nil, fmt.Errorf("package manager %s is not available", preferredPackageManager)
+				}
+				return m, nil
+			}
 		}
-		if IsCommandAvailable("yum") {
-			return NewYumManager(), nil
+	}
+
+	for _, m := range systemPackageManagers() {
+		if m.IsAvailable() {
+			return m, nil
 		}
 	}
+	if runtime.GOOS == "darwin" {
+		return nil, ErrHomebrewMissing
+	}
 
 	return nil, fmt.Errorf("no supported package manager found")
 }
 
-// IsCommandAvailable checks if a command is available in PATH
+// FindOwner returns the package manager that installed pkg: devbox, then the available system
+// package managers, then the vendor installers. It returns nil when pkg is not installed by any of them.
+func FindOwner(pkg string) PackageManager {
+	candidates := []PackageManager{NewDevboxManager()}
+	candidates = append(candidates, systemPackageManagers()...)
+	candidates = append(candidates, NewVendorManager())
+
+	for _, m := range candidates {
+		if m.IsAvailable() && m.IsInstalled(pkg) {
+			return m
+		}
+	}
+	return nil
+}
+
+// IsCommandAvailable checks if a command is available in PATH.
+// In plan mode, commands provided by planned installs count as available.
 func IsCommandAvailable(cmd string) bool {
-	_, err := exec.LookPath(cmd)
+	if planner != nil && planner.provided[cmd] {
+		return true
+	}
+	_, err := lookPath(cmd)
 	return err == nil
 }
+
+// queryOutput runs a read-only query command and returns its trimmed output; it also runs in plan mode.
+// A failure carries what the command printed on stderr.
+func queryOutput(name string, args ...string) (string, error) {
+	var out, errOut bytes.Buffer
+	cmd := command(name, args...)
+	cmd.Stdout = &out
+	cmd.Stderr = &errOut
+
+	err := run(cmd)
+	if err != nil && strings.TrimSpace(errOut.String()) != "" {
+		err = &CommandError{Err: err, Output: strings.Split(strings.TrimSpace(errOut.String()), "\n")}
+	}
+	return strings.TrimSpace(out.String()), err
+}
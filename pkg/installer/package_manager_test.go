@@ -0,0 +1,16 @@
+package installer_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/whexy/wenxuan-dev-init/pkg/installer"
+	"github.com/whexy/wenxuan-dev-init/pkg/profile"
+)
+
+// Profiles validate package managers without depending on the installer, so the two lists must agree
+func TestProfilePackageManagers(t *testing.T) {
+	if got := installer.PackageManagerNames(); !slices.Equal(got, profile.PackageManagers) {
+		t.Errorf("PackageManagerNames() = %q, but profiles accept %q", got, profile.PackageManagers)
+	}
+}
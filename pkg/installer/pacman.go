@@ -2,7 +2,7 @@ package installer
 
 import (
 	"fmt"
-	"os/exec"
+	"strings"
 )
 
 type PacmanManager struct{}
@@ -20,11 +20,84 @@ func (p *PacmanManager) IsAvailable() bool {
 }
 
 func (p *PacmanManager) Install(packages ...string) error {
-	args := append([]string{"-S", "--noconfirm"}, packages...)
-	cmd := exec.Command("sudo", append([]string{"pacman"}, args...)...)
-	cmd.Stdout = nil
-	cmd.Stderr = nil
+	// 1password-cli is only in the AUR
+	native, other := resolveAll("pacman", packages)
 
-	fmt.Printf("Running: sudo pacman -S --noconfirm %v\n", packages)
-	return cmd.Run()
+	// Arch does not support partial upgrades, so syncing the databases always comes with a full upgrade
+	// in the same transaction; this also runs without native packages so AUR builds see current databases
+	names := recipeNames(native)
+	cmd := sudoCommand("pacman", append([]string{"-Syu", "--needed", "--noconfirm"}, names...)...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	fmt.Fprintf(stdout, "Running: sudo pacman -Syu --needed --noconfirm %v\n", names)
+	if err := runCommand(cmd); err != nil {
+		return fmt.Errorf("failed to install %v: %w", names, err)
+	}
+
+	if err := installOther(other); err != nil {
+		return err
+	}
+
+	// Arch does not start services on install
+	for _, service := range recipeServices(native) {
+		if err := enableSystemdService(service); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsInstalled also covers AUR packages, which pacman tracks like any other
+func (p *PacmanManager) IsInstalled(pkg string) bool {
+	_, err := queryOutput("pacman", "-Q", resolve("pacman", pkg).Name)
+	return err == nil
+}
+
+func (p *PacmanManager) Version(pkg string) (string, error) {
+	out, err := queryOutput("pacman", "-Q", resolve("pacman", pkg).Name)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", pkg, ErrNotInstalled)
+	}
+	// pacman -Q prints "name version"
+	fields := strings.Fields(out)
+	if len(fields) < 2 {
+		return "", fmt.Errorf("%s: no version in %q", pkg, out)
+	}
+	return fields[len(fields)-1], nil
+}
+
+func (p *PacmanManager) Upgrade(packages ...string) error {
+	native, other := resolveAll("pacman", packages)
+
+	// A full upgrade brings the named packages up to date along with everything they depend on
+	names := recipeNames(native)
+	cmd := sudoCommand("pacman", append([]string{"-Syu", "--needed", "--noconfirm"}, names...)...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	fmt.Fprintf(stdout, "Running: sudo pacman -Syu --needed --noconfirm %v\n", names)
+	if err := runCommand(cmd); err != nil {
+		return fmt.Errorf("failed to upgrade %v: %w", names, err)
+	}
+
+	// AUR packages are rebuilt from the latest PKGBUILD
+	return installOther(other)
+}
+
+func (p *PacmanManager) Remove(packages ...string) error {
+	var names []string
+	for _, pkg := range packages {
+		names = append(names, resolve("pacman", pkg).Name)
+	}
+
+	cmd := sudoCommand("pacman", append([]string{"-Rns", "--noconfirm"}, names...)...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	fmt.Fprintf(stdout, "Running: sudo pacman -Rns --noconfirm %v\n", names)
+	if err := runCommand(cmd); err != nil {
+		return fmt.Errorf("failed to remove %v: %w", names, err)
+	}
+	return nil
 }
@@ -0,0 +1,236 @@
+package installer
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/whexy/wenxuan-dev-init/pkg/events"
+	"github.com/whexy/wenxuan-dev-init/pkg/ui"
+)
+
+// Action kinds recorded in a plan
+const (
+	ActionCommand  = "command"
+	ActionDownload = "download"
+	ActionFile     = "file"
+	ActionRepo     = "repo"
+	ActionSecret   = "secret"
+	ActionPrompt   = "prompt"
+)
+
+// Action describes a single change the installer makes to the system
+type Action struct {
+	Kind    string   `json:"kind"`
+	Summary string   `json:"summary"`
+	Command []string `json:"command,omitempty"`
+	Path    string   `json:"path,omitempty"`
+}
+
+// PlanStep groups the actions of one executor step
+type PlanStep struct {
+	Title   string   `json:"title"`
+	Actions []Action `json:"actions"`
+	Notes   []string `json:"notes,omitempty"`
+}
+
+// Plan is the list of actions a run would perform, recorded without touching the system
+type Plan struct {
+	PackageManager string      `json:"package_manager"`
+	Steps          []*PlanStep `json:"steps"`
+
+	provided map[string]bool
+}
+
+var (
+	planner *Plan
+
+	stdout io.Writer = events.NewWriter(events.StreamStdout)
+	stderr io.Writer = events.NewWriter(events.StreamStderr)
+)
+
+// SetOutput redirects installer messages and subprocess output to w and returns a function that restores the previous writers.
+// By default they are published as output events.
+func SetOutput(w io.Writer) (restore func()) {
+	prevOut, prevErr := stdout, stderr
+	stdout = w
+	stderr = w
+	return func() {
+		stdout, stderr = prevOut, prevErr
+	}
+}
+
+// flushOutput publishes partial lines left behind by a finished command
+func flushOutput() {
+	for _, w := range []io.Writer{stdout, stderr} {
+		if f, ok := w.(interface{ Flush() }); ok {
+			f.Flush()
+		}
+	}
+}
+
+// StartPlan switches the installer into plan mode.
+// Until StopPlan is called, commands, downloads, file writes and secret reads are recorded instead of performed.
+func StartPlan() *Plan {
+	planner = &Plan{provided: make(map[string]bool)}
+	return planner
+}
+
+// StopPlan leaves plan mode
+func StopPlan() {
+	planner = nil
+}
+
+// IsPlanning reports whether the installer is recording a plan
+func IsPlanning() bool {
+	return planner != nil
+}
+
+// BeginPlanStep starts a new step in the current plan; it is a no-op outside plan mode
+func BeginPlanStep(title string) {
+	if planner == nil {
+		return
+	}
+	planner.Steps = append(planner.Steps, &PlanStep{Title: title, Actions: []Action{}})
+}
+
+// PlanNote attaches a note to the current plan step; it is a no-op outside plan mode
+func PlanNote(note string) {
+	if planner == nil {
+		return
+	}
+	step := planner.current()
+	step.Notes = append(step.Notes, note)
+}
+
+// InstallPackages installs packages with pkgMgr and records the ones that were not installed before in the manifest.
+// In plan mode the binaries the packages provide are treated as available afterwards.
+func InstallPackages(pkgMgr PackageManager, packages ...string) error {
+	missing := missingPackages(pkgMgr, packages)
+	if err := pkgMgr.Install(packages...); err != nil {
+		return err
+	}
+	rememberPackages(pkgMgr, missing)
+	if planner != nil {
+		for _, pkg := range packages {
+			planner.provide(pkg)
+		}
+	}
+	return nil
+}
+
+func (p *Plan) current() *PlanStep {
+	if len(p.Steps) == 0 {
+		p.Steps = append(p.Steps, &PlanStep{Title: "Prepare", Actions: []Action{}})
+	}
+	return p.Steps[len(p.Steps)-1]
+}
+
+func (p *Plan) record(a Action) {
+	step := p.current()
+	step.Actions = append(step.Actions, a)
+}
+
+func (p *Plan) provide(pkg string) {
+	p.provided[ToolBinary(pkg)] = true
+}
+
+// WriteText renders the plan for humans
+func (p *Plan) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "Package manager: %s\n", p.PackageManager)
+	for _, step := range p.Steps {
+		fmt.Fprintf(w, "\n%s\n", step.Title)
+		if len(step.Actions) == 0 && len(step.Notes) == 0 {
+			fmt.Fprintln(w, "   (nothing to do)")
+		}
+		for _, a := range step.Actions {
+			switch {
+			case a.Kind == ActionCommand:
+				fmt.Fprintf(w, "   $ %s\n", a.Summary)
+			case a.Path != "":
+				fmt.Fprintf(w, "   [%s] %s (%s)\n", a.Kind, a.Summary, a.Path)
+			default:
+				fmt.Fprintf(w, "   [%s] %s\n", a.Kind, a.Summary)
+			}
+		}
+		for _, note := range step.Notes {
+			fmt.Fprintf(w, "   note: %s\n", note)
+		}
+	}
+}
+
+// runCommand runs cmd, or records it in plan mode
+func runCommand(cmd *Command) error {
+	return runAction(Action{Kind: ActionCommand}, cmd)
+}
+
+// runAction runs cmd, or records it in plan mode under the given action description
+func runAction(a Action, cmd *Command) error {
+	a.Command = cmd.Line()
+	if a.Summary == "" {
+		a.Summary = strings.Join(a.Command, " ")
+	}
+	if planner != nil {
+		planner.record(a)
+		return nil
+	}
+
+	events.Publish(events.Event{Kind: events.CommandStarted, Command: a.Summary})
+	defer flushOutput()
+	return execute(cmd)
+}
+
+// runInteractive runs cmd attached to the terminal, or records it in plan mode.
+// The banner is printed on the terminal right before the command starts.
+func runInteractive(cmd *Command, banner string) error {
+	if planner != nil {
+		return runCommand(cmd)
+	}
+
+	summary := strings.Join(cmd.Line(), " ")
+	events.Publish(events.Event{Kind: events.CommandStarted, Command: summary})
+	events.Publish(events.Event{Kind: events.PromptRequested, Prompt: cmp.Or(banner, summary)})
+
+	return ui.WithTerminal(func() error {
+		if banner != "" {
+			fmt.Println(banner)
+		}
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		return run(cmd)
+	})
+}
+
+// writeFile writes data to path, creating parent directories, or records the write in plan mode
+func writeFile(path string, data []byte, perm os.FileMode) error {
+	if planner != nil {
+		verb := "Create"
+		if _, err := files.Stat(path); err == nil {
+			verb = "Overwrite"
+		}
+		planner.record(Action{Kind: ActionFile, Summary: verb + " file", Path: path})
+		return nil
+	}
+
+	if err := files.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	return files.WriteFile(path, data, perm)
+}
+
+// appendFile appends data to path, creating it and its parent directories if needed, or records the change in plan mode
+func appendFile(path string, data []byte, perm os.FileMode) error {
+	if planner != nil {
+		planner.record(Action{Kind: ActionFile, Summary: "Append to file", Path: path})
+		return nil
+	}
+
+	if err := files.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	return files.AppendFile(path, data, perm)
+}
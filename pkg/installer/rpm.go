@@ -0,0 +1,250 @@
+package installer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RPMManager installs packages with dnf or yum, which share the rpm database and the repositories in /etc/yum.repos.d
+type RPMManager struct {
+	// tool is the dnf or yum command
+	tool string
+	// upgrade is the tool's upgrade command: dnf upgrade, or yum update
+	upgrade string
+}
+
+// NewRPMManager returns the rpm package manager that runs tool and upgrades with its upgrade command
+func NewRPMManager(tool, upgrade string) *RPMManager {
+	return &RPMManager{tool: tool, upgrade: upgrade}
+}
+
+func NewDnfManager() *RPMManager {
+	return NewRPMManager("dnf", "upgrade")
+}
+
+func NewYumManager() *RPMManager {
+	return NewRPMManager("yum", "update")
+}
+
+func (m *RPMManager) Name() string {
+	return m.tool
+}
+
+func (m *RPMManager) IsAvailable() bool {
+	return IsCommandAvailable(m.tool)
+}
+
+func (m *RPMManager) Install(packages ...string) error {
+	native, other := resolveAll(m.tool, packages)
+
+	if len(native) > 0 {
+		// gh, 1password-cli and tailscale come from vendor repositories
+		if err := ensureRPMRepos(m.tool, recipeRepos(native)); err != nil {
+			return err
+		}
+
+		names := recipeNames(native)
+		if err := m.run("install", names); err != nil {
+			return fmt.Errorf("failed to install %v: %w", names, err)
+		}
+
+		// rpm packages do not start their services
+		for _, service := range recipeServices(native) {
+			if err := enableSystemdService(service); err != nil {
+				return err
+			}
+		}
+	}
+
+	return installOther(other)
+}
+
+func (m *RPMManager) IsInstalled(pkg string) bool {
+	r := resolve(m.tool, pkg)
+	if r.Method != methodPackage {
+		return otherInstalled(r)
+	}
+	return rpmInstalled(r.Name)
+}
+
+func (m *RPMManager) Version(pkg string) (string, error) {
+	r := resolve(m.tool, pkg)
+	if r.Method != methodPackage {
+		return otherVersion(r)
+	}
+	return rpmVersion(r.Name)
+}
+
+func (m *RPMManager) Upgrade(packages ...string) error {
+	native, other := resolveAll(m.tool, packages)
+
+	if len(native) > 0 {
+		names := recipeNames(native)
+		if err := m.run(m.upgrade, names); err != nil {
+			return fmt.Errorf("failed to upgrade %v: %w", names, err)
+		}
+	}
+
+	return installOther(other)
+}
+
+func (m *RPMManager) Remove(packages ...string) error {
+	native, other := resolveAll(m.tool, packages)
+
+	if len(native) > 0 {
+		names := recipeNames(native)
+		if err := m.run("remove", names); err != nil {
+			return fmt.Errorf("failed to remove %v: %w", names, err)
+		}
+	}
+
+	return removeOther(other)
+}
+
+// run runs `sudo <tool> <verb> -y names...`
+func (m *RPMManager) run(verb string, names []string) error {
+	cmd := sudoCommand(m.tool, append([]string{verb, "-y"}, names...)...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	fmt.Fprintf(stdout, "Running: sudo %s %s -y %v\n", m.tool, verb, names)
+	return runCommand(cmd)
+}
+
+const yumReposDir = "/etc/yum.repos.d"
+
+// rpmRepo is a vendor repository for packages that are not in the Fedora, RHEL or CentOS repositories.
+// Its catalog key names the file in /etc/yum.repos.d.
+type rpmRepo struct {
+	Name string `yaml:"name"`
+	// RepoFile is the vendor's .repo file; without it a .repo file is written from BaseURL
+	RepoFile string `yaml:"repo_file"`
+	BaseURL  string `yaml:"base_url"`
+	// Key is the signing key, imported with rpm --import before the repository is added
+	Key string `yaml:"key"`
+}
+
+// ensureRPMRepos adds the vendor repositories from the catalog; tool is "dnf" or "yum"
+func ensureRPMRepos(tool string, ids []string) error {
+	vars := repoVars()
+	for _, id := range ids {
+		repo := tools.Repos.RPM[id]
+		if err := addRPMRepo(tool, id, repo, vars); err != nil {
+			return fmt.Errorf("failed to add %s repository: %w", repo.Name, err)
+		}
+	}
+	return nil
+}
+
+// addRPMRepo imports the repository key and adds the repository unless it is already configured
+func addRPMRepo(tool, id string, repo rpmRepo, vars *strings.Replacer) error {
+	path := yumReposDir + "/" + id + ".repo"
+	if _, err := files.Stat(path); err == nil {
+		return nil
+	}
+
+	fmt.Fprintf(stdout, "Setting up %s repository...\n", repo.Name)
+
+	key := vars.Replace(repo.Key)
+	if key != "" {
+		keyCmd := sudoCommand("rpm", "--import", key)
+		keyCmd.Stdout = stdout
+		keyCmd.Stderr = stderr
+		if err := runAction(Action{Kind: ActionFile, Summary: fmt.Sprintf("Import %s signing key %s", repo.Name, key)}, keyCmd); err != nil {
+			return fmt.Errorf("failed to import signing key: %w", err)
+		}
+	}
+
+	if repo.RepoFile == "" {
+		baseURL := vars.Replace(repo.BaseURL)
+		content := fmt.Sprintf("[%s]\nname=%s\nbaseurl=%s\nenabled=1\ngpgcheck=1\nrepo_gpgcheck=1\ngpgkey=%s\n", id, repo.Name, baseURL, key)
+		return trackFile(path, func() error {
+			return writeRootFile(path, content, Action{Kind: ActionRepo, Summary: "Add rpm repository " + baseURL})
+		})
+	}
+
+	repoFile := vars.Replace(repo.RepoFile)
+	if args := configManagerAddRepo(tool, repoFile); args != nil {
+		cmd := sudoCommand(args[0], args[1:]...)
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+		return trackFile(path, func() error {
+			return runAction(Action{Kind: ActionRepo, Summary: "Add rpm repository " + repoFile, Path: path}, cmd)
+		})
+	}
+
+	// No config-manager plugin: fetch the .repo file ourselves
+	if planner != nil {
+		planner.record(Action{Kind: ActionRepo, Summary: "Add rpm repository " + repoFile, Path: path})
+		return nil
+	}
+	content, err := httpGet(repoFile)
+	if err != nil {
+		return err
+	}
+	return trackFile(path, func() error {
+		return writeRootFile(path, string(content), Action{Kind: ActionRepo, Summary: "Add rpm repository " + repoFile})
+	})
+}
+
+// configManagerAddRepo returns the config-manager command that adds a .repo file, or nil when the plugin is missing
+func configManagerAddRepo(tool, repoFile string) []string {
+	switch tool {
+	case "dnf":
+		if IsCommandAvailable("dnf5") {
+			return []string{"dnf", "config-manager", "addrepo", "--from-repofile=" + repoFile}
+		}
+		// dnf 4 has config-manager only with dnf-plugins-core
+		probe := command("dnf", "config-manager", "--help")
+		probe.Stdout = io.Discard
+		probe.Stderr = io.Discard
+		if run(probe) == nil {
+			return []string{"dnf", "config-manager", "--add-repo", repoFile}
+		}
+	case "yum":
+		if IsCommandAvailable("yum-config-manager") {
+			return []string{"yum-config-manager", "--add-repo", repoFile}
+		}
+	}
+	return nil
+}
+
+// writeRootFile writes content to a root-owned path through sudo tee
+func writeRootFile(path, content string, a Action) error {
+	a.Path = path
+	cmd := sudoCommand("tee", path)
+	cmd.Stdin = strings.NewReader(content)
+	cmd.Stdout = io.Discard
+	cmd.Stderr = stderr
+	return runAction(a, cmd)
+}
+
+// enableSystemdService enables a service and starts it now
+func enableSystemdService(service string) error {
+	cmd := sudoCommand("systemctl", "enable", "--now", service)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := runCommand(cmd); err != nil {
+		return fmt.Errorf("failed to start %s: %w", service, err)
+	}
+	return nil
+}
+
+// rpmInstalled reports whether the rpm database has the package
+func rpmInstalled(name string) bool {
+	_, err := queryOutput("rpm", "-q", name)
+	return err == nil
+}
+
+// rpmVersion returns the version and release of an installed rpm package
+func rpmVersion(name string) (string, error) {
+	if !rpmInstalled(name) {
+		return "", fmt.Errorf("%s: %w", name, ErrNotInstalled)
+	}
+	version, err := queryOutput("rpm", "-q", "--qf", "%{VERSION}-%{RELEASE}", name)
+	if err == nil && version == "" {
+		err = fmt.Errorf("%s: no version from rpm", name)
+	}
+	return version, err
+}
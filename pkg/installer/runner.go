@@ -0,0 +1,212 @@
+package installer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Command is a command for a Runner. Args[0] is the program, as in exec.Cmd.
+type Command struct {
+	Args []string
+	// Dir is the working directory; empty runs in the current one
+	Dir string
+	// Env holds KEY=VALUE pairs added to the environment of this process
+	Env    []string
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	// Privileged runs the command as root: through sudo, unless this process already is root
+	Privileged bool
+}
+
+// Line returns the command line as it runs on this machine, with sudo for privileged commands
+func (c *Command) Line() []string {
+	if c.Privileged && !isRoot() {
+		return append([]string{"sudo"}, c.Args...)
+	}
+	return c.Args
+}
+
+// Runner runs the commands of the installer
+type Runner interface {
+	// Run runs cmd and waits for it to finish; a non-zero exit is an error with an ExitCode method
+	Run(ctx context.Context, cmd Command) error
+}
+
+// ExecRunner runs commands on this machine
+type ExecRunner struct{}
+
+func (ExecRunner) Run(ctx context.Context, cmd Command) error {
+	line := cmd.Line()
+	c := exec.CommandContext(ctx, line[0], line[1:]...)
+	c.Dir = cmd.Dir
+	if len(cmd.Env) > 0 {
+		c.Env = append(os.Environ(), cmd.Env...)
+	}
+	c.Stdin = cmd.Stdin
+	c.Stdout = cmd.Stdout
+	c.Stderr = cmd.Stderr
+	return c.Run()
+}
+
+// runner runs every command of the installer
+var runner Runner = ExecRunner{}
+
+// SetRunner makes the installer run its commands with r; nil restores ExecRunner
+func SetRunner(r Runner) {
+	if r == nil {
+		r = ExecRunner{}
+	}
+	runner = r
+}
+
+// runContext cancels the commands that are running when it is done
+var runContext = context.Background()
+
+// SetContext makes the installer run its commands under ctx, so cancelling ctx stops them; nil restores the background context
+func SetContext(ctx context.Context) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	runContext = ctx
+}
+
+// command builds a command that runs as the current user
+func command(name string, args ...string) *Command {
+	return &Command{Args: append([]string{name}, args...)}
+}
+
+// sudoCommand builds a command that runs as root, through sudo unless the process already is root
+func sudoCommand(name string, args ...string) *Command {
+	cmd := command(name, args...)
+	cmd.Privileged = true
+	return cmd
+}
+
+// run runs cmd with the runner
+func run(cmd *Command) error {
+	return runner.Run(runContext, *cmd)
+}
+
+// output runs cmd and returns what it printed on stdout. Failures keep the end of stderr, like execute.
+func output(cmd *Command) ([]byte, error) {
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	err := execute(cmd)
+	return out.Bytes(), err
+}
+
+// combinedOutput runs cmd and returns what it printed on stdout and stderr
+func combinedOutput(cmd *Command) ([]byte, error) {
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := run(cmd)
+	return out.Bytes(), err
+}
+
+// exitCode returns the exit code of a command that ran and failed, or -1
+func exitCode(err error) int {
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// outputTailLines is how many lines of output a failed command's error keeps
+const outputTailLines = 20
+
+// CommandError is returned when a command fails and carries the last lines it printed
+type CommandError struct {
+	Err    error
+	Output []string
+}
+
+func (e *CommandError) Error() string {
+	if len(e.Output) == 0 {
+		return e.Err.Error()
+	}
+	return e.Err.Error() + "\n" + strings.Join(e.Output, "\n")
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// tailBuffer keeps the last lines written to its streams
+type tailBuffer struct {
+	mu    sync.Mutex
+	lines []string
+	max   int
+}
+
+func (t *tailBuffer) add(line string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	t.lines = append(t.lines, line)
+	if len(t.lines) > t.max {
+		t.lines = t.lines[len(t.lines)-t.max:]
+	}
+}
+
+// tailStream splits one output stream into lines for a tailBuffer.
+// Carriage returns end a line too so progress bars don't pile up.
+type tailStream struct {
+	tail    *tailBuffer
+	partial []byte
+}
+
+func (s *tailStream) Write(p []byte) (int, error) {
+	s.partial = append(s.partial, p...)
+	for {
+		i := bytes.IndexAny(s.partial, "\r\n")
+		if i < 0 {
+			break
+		}
+		s.tail.add(string(s.partial[:i]))
+		s.partial = s.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush keeps a trailing partial line
+func (s *tailStream) Flush() {
+	s.tail.add(string(s.partial))
+	s.partial = nil
+}
+
+// execute runs cmd, keeping the end of its output for the error if it fails.
+// Output still goes wherever cmd.Stdout and cmd.Stderr point; nil outputs are only kept.
+func execute(cmd *Command) error {
+	tail := &tailBuffer{max: outputTailLines}
+	out := &tailStream{tail: tail}
+	errOut := &tailStream{tail: tail}
+	cmd.Stdout = teeOutput(cmd.Stdout, out)
+	cmd.Stderr = teeOutput(cmd.Stderr, errOut)
+
+	err := run(cmd)
+	if err == nil {
+		return nil
+	}
+	out.Flush()
+	errOut.Flush()
+	return &CommandError{Err: err, Output: tail.lines}
+}
+
+func teeOutput(w io.Writer, s *tailStream) io.Writer {
+	if w == nil {
+		return s
+	}
+	return io.MultiWriter(w, s)
+}
@@ -0,0 +1,278 @@
+package installer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// envChange is one variable assignment or removal from a shell environment script
+type envChange struct {
+	name  string
+	value string
+	unset bool
+}
+
+// parseShellenv extracts the export and unset statements from POSIX shell output such as `devbox global shellenv`
+// or `brew shellenv`. Values may be bare, single-quoted or double-quoted, and variables in them are expanded with lookup;
+// other statements are ignored.
+func parseShellenv(script string, lookup func(string) (string, bool)) ([]envChange, error) {
+	var changes []envChange
+	// Later statements see the values set by earlier ones
+	env := func(name string) (string, bool) {
+		for i := len(changes) - 1; i >= 0; i-- {
+			if changes[i].name == name {
+				return changes[i].value, !changes[i].unset
+			}
+		}
+		return lookup(name)
+	}
+
+	for _, stmt := range splitStatements(script) {
+		words, err := shellWords(stmt, env)
+		if err != nil {
+			return nil, err
+		}
+		if len(words) < 2 {
+			continue
+		}
+
+		switch words[0] {
+		case "export":
+			for _, word := range words[1:] {
+				name, value, ok := strings.Cut(word, "=")
+				if !ok || !isEnvName(name) {
+					continue
+				}
+				changes = append(changes, envChange{name: name, value: value})
+			}
+		case "unset":
+			for _, name := range words[1:] {
+				if isEnvName(name) {
+					changes = append(changes, envChange{name: name, unset: true})
+				}
+			}
+		}
+	}
+	return changes, nil
+}
+
+// splitStatements splits a script on newlines and semicolons that are not quoted
+func splitStatements(script string) []string {
+	var stmts []string
+	var cur strings.Builder
+	var quote rune
+	escaped := false
+
+	for _, r := range script {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == ';' || r == '\n':
+			stmts = append(stmts, cur.String())
+			cur.Reset()
+			continue
+		}
+		cur.WriteRune(r)
+	}
+	return append(stmts, cur.String())
+}
+
+// shellWords splits a statement into words, removing quotes and backslash escapes and expanding
+// $NAME and ${NAME} outside single quotes with lookup
+func shellWords(stmt string, lookup func(string) (string, bool)) ([]string, error) {
+	var words []string
+	var cur strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+
+	runes := []rune(stmt)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case escaped:
+			escaped = false
+			// Inside double quotes a backslash only escapes characters that are special there
+			if quote == '"' && !strings.ContainsRune("$`\"\\\n", r) {
+				cur.WriteRune('\\')
+			}
+			cur.WriteRune(r)
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\\':
+			escaped = true
+			inWord = true
+		case r == '$':
+			value, n, err := expand(runes[i+1:], lookup)
+			if err != nil {
+				return nil, fmt.Errorf("%w in %q", err, stmt)
+			}
+			if n == 0 {
+				cur.WriteRune(r)
+			} else {
+				cur.WriteString(value)
+				i += n
+			}
+			inWord = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\r':
+			if inWord {
+				words = append(words, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", stmt)
+	}
+	if inWord {
+		words = append(words, cur.String())
+	}
+	return words, nil
+}
+
+// expand reads the expansion after a $ and returns its value and how many runes it spans;
+// it spans none when the $ is a literal dollar sign.
+// Besides $NAME and ${NAME} it understands the forms shellenv scripts use to join paths:
+// ${NAME-word}, ${NAME:-word}, ${NAME+word}, ${NAME:+word}, ${NAME#prefix} and ${NAME%suffix}.
+func expand(rest []rune, lookup func(string) (string, bool)) (string, int, error) {
+	if len(rest) == 0 || rest[0] != '{' {
+		n := 0
+		for n < len(rest) && isEnvName(string(rest[:n+1])) {
+			n++
+		}
+		value, _ := lookup(string(rest[:n]))
+		return value, n, nil
+	}
+
+	end := -1
+	depth := 0
+	for i, r := range rest {
+		if r == '{' {
+			depth++
+		} else if r == '}' {
+			if depth--; depth == 0 {
+				end = i
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return "", 0, fmt.Errorf("unterminated ${")
+	}
+	expr := string(rest[1:end])
+
+	n := 0
+	for n < len(expr) && isEnvName(expr[:n+1]) {
+		n++
+	}
+	if n == 0 {
+		return "", 0, fmt.Errorf("bad substitution ${%s}", expr)
+	}
+	value, set := lookup(expr[:n])
+	op := expr[n:]
+	if op == "" {
+		return value, end + 1, nil
+	}
+
+	var operator string
+	for _, o := range []string{":-", ":+", "-", "+", "#", "%"} {
+		if strings.HasPrefix(op, o) {
+			operator = o
+			break
+		}
+	}
+	if operator == "" {
+		return "", 0, fmt.Errorf("unsupported expansion ${%s}", expr)
+	}
+	word, err := expandText(op[len(operator):], lookup)
+	if err != nil {
+		return "", 0, err
+	}
+
+	switch operator {
+	case "-":
+		if !set {
+			value = word
+		}
+	case ":-":
+		if value == "" {
+			value = word
+		}
+	case "+":
+		value = ""
+		if set {
+			value = word
+		}
+	case ":+":
+		if value != "" {
+			value = word
+		}
+	case "#":
+		value = strings.TrimPrefix(value, word)
+	case "%":
+		value = strings.TrimSuffix(value, word)
+	}
+	return value, end + 1, nil
+}
+
+// expandText expands the variables in the word of a ${NAME-word} expansion
+func expandText(s string, lookup func(string) (string, bool)) (string, error) {
+	var b strings.Builder
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '$' {
+			b.WriteRune(runes[i])
+			continue
+		}
+		value, n, err := expand(runes[i+1:], lookup)
+		if err != nil {
+			return "", err
+		}
+		if n == 0 {
+			b.WriteRune('$')
+		}
+		b.WriteString(value)
+		i += n
+	}
+	return b.String(), nil
+}
+
+func isEnvName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_', r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
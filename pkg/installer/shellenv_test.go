@@ -0,0 +1,209 @@
+package installer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// mapEnv looks variables up in env the way os.LookupEnv does
+func mapEnv(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+}
+
+func TestParseShellenv(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		env    map[string]string
+		want   []envChange
+	}{
+		{
+			// `brew shellenv` with SHELL=bash on Linux, Homebrew 4.4
+			name: "brew shellenv",
+			script: `export HOMEBREW_PREFIX="/home/linuxbrew/.linuxbrew";
+export HOMEBREW_CELLAR="/home/linuxbrew/.linuxbrew/Cellar";
+export HOMEBREW_REPOSITORY="/home/linuxbrew/.linuxbrew/Homebrew";
+export PATH="/home/linuxbrew/.linuxbrew/bin:/home/linuxbrew/.linuxbrew/sbin${PATH+:$PATH}";
+[ -z "${MANPATH-}" ] || export MANPATH=":${MANPATH#:}";
+export INFOPATH="/home/linuxbrew/.linuxbrew/share/info:${INFOPATH:-}";
+`,
+			env: map[string]string{"PATH": "/usr/bin:/bin"},
+			want: []envChange{
+				{name: "HOMEBREW_PREFIX", value: "/home/linuxbrew/.linuxbrew"},
+				{name: "HOMEBREW_CELLAR", value: "/home/linuxbrew/.linuxbrew/Cellar"},
+				{name: "HOMEBREW_REPOSITORY", value: "/home/linuxbrew/.linuxbrew/Homebrew"},
+				{name: "PATH", value: "/home/linuxbrew/.linuxbrew/bin:/home/linuxbrew/.linuxbrew/sbin:/usr/bin:/bin"},
+				{name: "INFOPATH", value: "/home/linuxbrew/.linuxbrew/share/info:"},
+			},
+		},
+		{
+			name:   "brew shellenv without PATH",
+			script: `export PATH="/opt/homebrew/bin:/opt/homebrew/sbin${PATH+:$PATH}";`,
+			want:   []envChange{{name: "PATH", value: "/opt/homebrew/bin:/opt/homebrew/sbin"}},
+		},
+		{
+			// `devbox global shellenv`, devbox 0.13
+			name: "devbox global shellenv",
+			script: `export DEVBOX_PROJECT_ROOT="/home/wx/.local/share/devbox/global/default";
+export DEVBOX_WD="/home/wx";
+export NIX_PROFILES="/nix/var/nix/profiles/default /home/wx/.nix-profile";
+export PATH="/home/wx/.local/share/devbox/global/default/.devbox/nix/profile/default/bin:$PATH";
+export PS1="\\u@\\h \$ ";
+
+hash -r
+`,
+			env: map[string]string{"PATH": "/usr/bin"},
+			want: []envChange{
+				{name: "DEVBOX_PROJECT_ROOT", value: "/home/wx/.local/share/devbox/global/default"},
+				{name: "DEVBOX_WD", value: "/home/wx"},
+				{name: "NIX_PROFILES", value: "/nix/var/nix/profiles/default /home/wx/.nix-profile"},
+				{name: "PATH", value: "/home/wx/.local/share/devbox/global/default/.devbox/nix/profile/default/bin:/usr/bin"},
+				{name: "PS1", value: `\u@\h $ `},
+			},
+		},
+		{
+			name:   "several assignments in one export",
+			script: "export A=B C=D",
+			want:   []envChange{{name: "A", value: "B"}, {name: "C", value: "D"}},
+		},
+		{
+			name:   "semicolon-separated statements see earlier values",
+			script: "export A=1; export B=${A}2;export C=$B'$B'",
+			want:   []envChange{{name: "A", value: "1"}, {name: "B", value: "12"}, {name: "C", value: "12$B"}},
+		},
+		{
+			name:   "unset",
+			script: "unset A B; export C=${A-none}",
+			env:    map[string]string{"A": "1", "B": "2"},
+			want:   []envChange{{name: "A", unset: true}, {name: "B", unset: true}, {name: "C", value: "none"}},
+		},
+		{
+			name:   "quoted semicolon",
+			script: `export A="x;y" B='p;q'`,
+			want:   []envChange{{name: "A", value: "x;y"}, {name: "B", value: "p;q"}},
+		},
+		{
+			name:   "other statements are ignored",
+			script: "echo export A=1\nexport\nexport 1A=2 B\nhash -r",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseShellenv(tt.script, mapEnv(tt.env))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseShellenv() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseShellenvErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{"unterminated double quote", `export A="x`, "unterminated quote"},
+		{"unterminated single quote", `export A='x`, "unterminated quote"},
+		{"unterminated brace", `export A="${PATH"`, "unterminated ${"},
+		{"empty brace", `export A=${}`, "bad substitution"},
+		{"unsupported operator", `export A=${PATH/:/ }`, "unsupported expansion"},
+		{"bad nested expansion", `export A=${B:-${}}`, "bad substitution"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseShellenv(tt.script, mapEnv(nil))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseShellenv(%q) error = %v, want %q", tt.script, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		script string
+		want   []string
+	}{
+		{"a;b\nc", []string{"a", "b", "c"}},
+		{`a "x;y" 'p;q';b`, []string{`a "x;y" 'p;q'`, "b"}},
+		{`a \; b`, []string{`a \; b`}},
+		{`a 'x\';b`, []string{`a 'x\'`, "b"}},
+		{"", []string{""}},
+	}
+
+	for _, tt := range tests {
+		if got := splitStatements(tt.script); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitStatements(%q) = %q, want %q", tt.script, got, tt.want)
+		}
+	}
+}
+
+func TestShellWords(t *testing.T) {
+	env := mapEnv(map[string]string{"HOME": "/home/wx", "EMPTY": ""})
+	tests := []struct {
+		stmt string
+		want []string
+	}{
+		{"  a \tb  ", []string{"a", "b"}},
+		{`'a b' "c d" e\ f`, []string{"a b", "c d", "e f"}},
+		{`'$HOME' "$HOME" $HOME`, []string{"$HOME", "/home/wx", "/home/wx"}},
+		{`"\$HOME \" \\ \a" \$ \a`, []string{`$HOME " \ \a`, "$", "a"}},
+		{`${HOME}/bin $HOME.d $HOMEx`, []string{"/home/wx/bin", "/home/wx.d", ""}},
+		{`"" ''`, []string{"", ""}},
+		{`$ a$ $1`, []string{"$", "a$", "$1"}},
+		{`${EMPTY-x} ${EMPTY:-x} ${UNSET-x}`, []string{"", "x", "x"}},
+		{`${EMPTY+x} ${EMPTY:+x} ${HOME:+x} ${UNSET+x}`, []string{"x", "", "x", ""}},
+		{`${HOME#/home} ${HOME%/wx} ${HOME#/nope}`, []string{"/wx", "/home", "/home/wx"}},
+		{`${UNSET:-$HOME/bin} ${UNSET:-${HOME}}`, []string{"/home/wx/bin", "/home/wx"}},
+	}
+
+	for _, tt := range tests {
+		got, err := shellWords(tt.stmt, env)
+		if err != nil {
+			t.Errorf("shellWords(%q): %v", tt.stmt, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("shellWords(%q) = %q, want %q", tt.stmt, got, tt.want)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	env := mapEnv(map[string]string{"PATH": "/bin", "A_1": "x"})
+	tests := []struct {
+		rest  string
+		value string
+		n     int
+	}{
+		{"PATH", "/bin", 4},
+		{"PATH:/usr/bin", "/bin", 4},
+		{"A_1b", "", 4},
+		{"{A_1}b", "x", 5},
+		{"{PATH+:$PATH}", ":/bin", 13},
+		{"1", "", 0},
+		{"", "", 0},
+		{"-", "", 0},
+	}
+
+	for _, tt := range tests {
+		value, n, err := expand([]rune(tt.rest), env)
+		if err != nil {
+			t.Errorf("expand(%q): %v", tt.rest, err)
+			continue
+		}
+		if value != tt.value || n != tt.n {
+			t.Errorf("expand(%q) = %q, %d, want %q, %d", tt.rest, value, n, tt.value, tt.n)
+		}
+	}
+}
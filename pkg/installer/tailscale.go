@@ -1,11 +1,9 @@
 package installer
 
 import (
-	"bytes"
 	"fmt"
-	"os"
-	"os/exec"
-	"strings"
+
+	"github.com/whexy/wenxuan-dev-init/pkg/state"
 )
 
 var (
@@ -17,23 +15,14 @@ func SetTailscaleAuthKeyReference(ref string) {
 	tailscaleAuthKeyReference = ref
 }
 
-// IsTailscaleSetup checks if Tailscale is configured and running
+// IsTailscaleSetup checks if Tailscale is logged in and connected
 func IsTailscaleSetup() bool {
 	if !IsCommandAvailable("tailscale") {
 		return false
 	}
 
-	// Run 'tailscale status' to check if it's set up
-	cmd := exec.Command("tailscale", "status")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return false
-	}
-
-	// If the output contains information about the tailscale network, it's set up
-	// An unconfigured tailscale will return an error or empty output
-	outputStr := strings.TrimSpace(string(output))
-	return outputStr != "" && !strings.Contains(outputStr, "Logged out")
+	st, err := TailscaleStatus()
+	return err == nil && st.BackendState == "Running"
 }
 
 // InstallTailscale installs the Tailscale client
@@ -43,7 +32,7 @@ func InstallTailscale(pkgMgr PackageManager) error {
 	}
 
 	// Install tailscale package
-	if err := pkgMgr.Install("tailscale"); err != nil {
+	if err := InstallPackages(pkgMgr, "tailscale"); err != nil {
 		return fmt.Errorf("failed to install tailscale: %w", err)
 	}
 
@@ -52,19 +41,12 @@ func InstallTailscale(pkgMgr PackageManager) error {
 
 // GetTailscaleAuthKeyFrom1Password retrieves the Tailscale auth key from 1Password
 func GetTailscaleAuthKeyFrom1Password() (string, error) {
-	fmt.Printf("Fetching Tailscale auth key from 1Password: %s\n", tailscaleAuthKeyReference)
-
-	// Read the secret from 1Password
-	cmd := exec.Command("op", "read", tailscaleAuthKeyReference)
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = os.Stderr
+	fmt.Fprintf(stdout, "Fetching Tailscale auth key from 1Password: %s\n", tailscaleAuthKeyReference)
 
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("failed to read from 1Password: %w", err)
+	authKey, err := readSecret(tailscaleAuthKeyReference)
+	if err != nil {
+		return "", err
 	}
-
-	authKey := strings.TrimSpace(out.String())
 	if authKey == "" {
 		return "", fmt.Errorf("empty auth key received from 1Password")
 	}
@@ -84,17 +66,17 @@ func SetupTailscale() error {
 		return fmt.Errorf("failed to get Tailscale auth key: %w", err)
 	}
 
-	fmt.Println("Connecting to Tailscale network...")
+	fmt.Fprintln(stdout, "Connecting to Tailscale network...")
 
 	// Run 'tailscale up' with the auth key
-	cmd := exec.Command("tailscale", "up", "--authkey", authKey)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd := command("tailscale", "up", "--authkey", authKey)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
 
-	if err := cmd.Run(); err != nil {
+	if err := runAction(Action{Kind: ActionCommand, Summary: "tailscale up --authkey <auth key>"}, cmd); err != nil {
 		return fmt.Errorf("failed to setup tailscale: %w", err)
 	}
+	rememberLogin(state.LoginTailscale, "")
 
 	return nil
 }
\ No newline at end of file
@@ -0,0 +1,86 @@
+package installer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/whexy/wenxuan-dev-init/pkg/state"
+)
+
+// Logout signs out of an account recorded in the manifest
+func Logout(login state.Login) error {
+	var cmd *Command
+	switch login.Service {
+	case state.LoginGitHub:
+		cmd = command("gh", "auth", "logout", "--hostname", login.Account)
+	case state.LoginTailscale:
+		cmd = command("tailscale", "logout")
+	case state.Login1Password:
+		cmd = command("op", "account", "forget", login.Account)
+	default:
+		return fmt.Errorf("unknown login %q", login.Service)
+	}
+
+	if !IsCommandAvailable(cmd.Args[0]) {
+		return fmt.Errorf("%s is not installed", cmd.Args[0])
+	}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return runCommand(cmd)
+}
+
+// RemoveFile deletes a file setup created; files outside the home directory are removed with sudo
+func RemoveFile(path string) error {
+	if _, err := files.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	home, _ := os.UserHomeDir()
+	if home != "" && strings.HasPrefix(path, home+string(filepath.Separator)) {
+		return files.Remove(path)
+	}
+
+	cmd := sudoCommand("rm", "-f", path)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return runAction(Action{Kind: ActionFile, Summary: "Remove file", Path: path}, cmd)
+}
+
+// RemoveAppend takes text setup appended out of a file again; the rest of the file is left alone
+func RemoveAppend(path, text string) error {
+	info, err := files.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	data, err := files.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	content := string(data)
+	i := strings.LastIndex(content, text)
+	if i < 0 {
+		return nil
+	}
+	return writeFile(path, []byte(content[:i]+content[i+len(text):]), info.Mode().Perm())
+}
+
+// PackageManagerByName returns the package manager with the given name, including devbox and the vendor installers
+func PackageManagerByName(name string) (PackageManager, error) {
+	candidates := append([]PackageManager{NewDevboxManager(), NewVendorManager()}, allSystemPackageManagers()...)
+	for _, m := range candidates {
+		if m.Name() == name {
+			if !m.IsAvailable() {
+				return nil, fmt.Errorf("package manager %s is not available", name)
+			}
+			return m, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown package manager %q", name)
+}
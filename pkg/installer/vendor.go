@@ -0,0 +1,78 @@
+package installer
+
+import "fmt"
+
+// VendorManager manages tools installed with their vendor's installer rather than a package manager,
+// such as chezmoi from get.chezmoi.io in /usr/local/bin or the 1Password CLI release binary
+type VendorManager struct{}
+
+func NewVendorManager() *VendorManager {
+	return &VendorManager{}
+}
+
+func (v *VendorManager) Name() string {
+	return "vendor"
+}
+
+func (v *VendorManager) IsAvailable() bool {
+	return true
+}
+
+// recipe returns the vendor install method of a catalog tool
+func (v *VendorManager) recipe(pkg string) (recipe, error) {
+	t, ok := tools.Tools[pkg]
+	switch {
+	case !ok:
+	case t.Script != nil:
+		return recipe{tool: pkg, Name: pkg, Method: methodScript}, nil
+	case t.Fallback != "" && t.Fallback != methodPackage:
+		return recipe{tool: pkg, Name: pkg, Method: t.Fallback}, nil
+	}
+	return recipe{}, fmt.Errorf("%s has no vendor installer", pkg)
+}
+
+func (v *VendorManager) recipes(packages []string) ([]recipe, error) {
+	var recipes []recipe
+	for _, pkg := range packages {
+		r, err := v.recipe(pkg)
+		if err != nil {
+			return nil, err
+		}
+		recipes = append(recipes, r)
+	}
+	return recipes, nil
+}
+
+func (v *VendorManager) Install(packages ...string) error {
+	recipes, err := v.recipes(packages)
+	if err != nil {
+		return err
+	}
+	return installOther(recipes)
+}
+
+func (v *VendorManager) IsInstalled(pkg string) bool {
+	r, err := v.recipe(pkg)
+	return err == nil && otherInstalled(r)
+}
+
+func (v *VendorManager) Version(pkg string) (string, error) {
+	r, err := v.recipe(pkg)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", pkg, ErrNotInstalled)
+	}
+	return otherVersion(r)
+}
+
+// Upgrade runs the vendor installers again, which fetch the latest release
+func (v *VendorManager) Upgrade(packages ...string) error {
+	return v.Install(packages...)
+}
+
+func (v *VendorManager) Remove(packages ...string) error {
+	recipes, err := v.recipes(packages)
+	if err != nil {
+		return err
+	}
+	return removeOther(recipes)
+}
@@ -0,0 +1,162 @@
+package installer_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/whexy/wenxuan-dev-init/pkg/installer"
+	"github.com/whexy/wenxuan-dev-init/pkg/installer/installertest"
+)
+
+func TestVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		manager func() installer.PackageManager
+		pkg     string
+		// respond fakes the package manager's query; nil leaves it failing as for a package that is not installed
+		respond       func(r *installertest.Runner)
+		want          string
+		wantInstalled bool
+		wantErr       error
+	}{
+		{
+			name:    "pacman",
+			manager: func() installer.PackageManager { return installer.NewPacmanManager() },
+			pkg:     "gh",
+			respond: func(r *installertest.Runner) {
+				r.Respond("pacman -Q github-cli", installertest.Response{Stdout: "github-cli 2.62.0-1\n"})
+			},
+			want:          "2.62.0-1",
+			wantInstalled: true,
+		},
+		{
+			name:    "pacman not installed",
+			manager: func() installer.PackageManager { return installer.NewPacmanManager() },
+			pkg:     "gh",
+			wantErr: installer.ErrNotInstalled,
+		},
+		{
+			name:    "pacman without a version",
+			manager: func() installer.PackageManager { return installer.NewPacmanManager() },
+			pkg:     "git",
+			respond: func(r *installertest.Runner) {
+				r.Respond("pacman -Q git", installertest.Response{})
+			},
+			wantInstalled: true,
+		},
+		{
+			name:    "dnf",
+			manager: func() installer.PackageManager { return installer.NewDnfManager() },
+			pkg:     "git",
+			respond: func(r *installertest.Runner) {
+				r.Respond("rpm -q git", installertest.Response{Stdout: "git-2.47.1-1.fc41.x86_64\n"})
+				r.Respond("rpm -q --qf", installertest.Response{Stdout: "2.47.1-1.fc41"})
+			},
+			want:          "2.47.1-1.fc41",
+			wantInstalled: true,
+		},
+		{
+			name:    "dnf not installed",
+			manager: func() installer.PackageManager { return installer.NewDnfManager() },
+			pkg:     "git",
+			wantErr: installer.ErrNotInstalled,
+		},
+		{
+			name:    "yum",
+			manager: func() installer.PackageManager { return installer.NewYumManager() },
+			pkg:     "gh",
+			respond: func(r *installertest.Runner) {
+				r.Respond("rpm -q gh", installertest.Response{Stdout: "gh-2.62.0-1.x86_64\n"})
+				r.Respond("rpm -q --qf", installertest.Response{Stdout: "2.62.0-1"})
+			},
+			want:          "2.62.0-1",
+			wantInstalled: true,
+		},
+		{
+			name:    "yum not installed",
+			manager: func() installer.PackageManager { return installer.NewYumManager() },
+			pkg:     "gh",
+			wantErr: installer.ErrNotInstalled,
+		},
+		{
+			name:    "brew",
+			manager: func() installer.PackageManager { return installer.NewBrewManager() },
+			pkg:     "gh",
+			respond: func(r *installertest.Runner) {
+				r.Respond("/usr/bin/brew list --versions --formula gh", installertest.Response{Stdout: "gh 2.61.0 2.62.0\n"})
+			},
+			want:          "2.62.0",
+			wantInstalled: true,
+		},
+		{
+			// brew list --versions succeeds without output for packages that are not installed
+			name:    "brew not installed",
+			manager: func() installer.PackageManager { return installer.NewBrewManager() },
+			pkg:     "gh",
+			respond: func(r *installertest.Runner) {
+				r.Respond("/usr/bin/brew list --versions", installertest.Response{})
+			},
+			wantErr: installer.ErrNotInstalled,
+		},
+		{
+			name:    "devbox",
+			manager: func() installer.PackageManager { return installer.NewDevboxManager() },
+			pkg:     "gh",
+			respond: func(r *installertest.Runner) {
+				r.Respond("devbox global list", installertest.Response{Stdout: "* git@latest - 2.47.1\n* gh@latest - 2.62.0\n"})
+			},
+			want:          "2.62.0",
+			wantInstalled: true,
+		},
+		{
+			name:    "devbox without resolved versions",
+			manager: func() installer.PackageManager { return installer.NewDevboxManager() },
+			pkg:     "gh",
+			respond: func(r *installertest.Runner) {
+				r.Respond("devbox global list", installertest.Response{Stdout: "* gh@2.62.0\n"})
+			},
+			want:          "2.62.0",
+			wantInstalled: true,
+		},
+		{
+			name:    "devbox not installed",
+			manager: func() installer.PackageManager { return installer.NewDevboxManager() },
+			pkg:     "gh",
+			respond: func(r *installertest.Runner) {
+				r.Respond("devbox global list", installertest.Response{Stdout: "* git@latest - 2.47.1\n"})
+			},
+			wantErr: installer.ErrNotInstalled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakes(t, "brew")
+			// Queries fail unless the test answers them
+			f.runner.Fail("", 1, "")
+			if tt.respond != nil {
+				tt.respond(f.runner)
+			}
+			m := tt.manager()
+
+			if got := m.IsInstalled(tt.pkg); got != tt.wantInstalled {
+				t.Errorf("IsInstalled(%q) = %v, want %v", tt.pkg, got, tt.wantInstalled)
+			}
+			got, err := m.Version(tt.pkg)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Version(%q) error = %v, want %v", tt.pkg, err, tt.wantErr)
+				}
+			case tt.want == "":
+				if err == nil {
+					t.Errorf("Version(%q) = %q, want an error", tt.pkg, got)
+				}
+			case err != nil:
+				t.Errorf("Version(%q): %v", tt.pkg, err)
+			case got != tt.want:
+				t.Errorf("Version(%q) = %q, want %q", tt.pkg, got, tt.want)
+			}
+		})
+	}
+}
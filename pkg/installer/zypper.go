@@ -0,0 +1,170 @@
+package installer
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ZypperManager struct{}
+
+func NewZypperManager() *ZypperManager {
+	return &ZypperManager{}
+}
+
+func (z *ZypperManager) Name() string {
+	return "zypper"
+}
+
+func (z *ZypperManager) IsAvailable() bool {
+	return IsCommandAvailable("zypper")
+}
+
+// zypperRepo is a third-party repository a package needs; its catalog key is the repository alias
+type zypperRepo struct {
+	Name string `yaml:"name"`
+	// URL is either a base URL or a .repo file
+	URL string `yaml:"url"`
+	// Key is imported with rpm --import before adding the repository; refreshes never trust keys on their own
+	Key string `yaml:"key"`
+}
+
+func (z *ZypperManager) Install(packages ...string) error {
+	native, other := resolveAll("zypper", packages)
+
+	if len(native) > 0 {
+		vars := repoVars()
+		aliases := recipeRepos(native)
+		for _, alias := range aliases {
+			repo := tools.Repos.Zypper[alias]
+			if err := addZypperRepo(alias, repo, vars); err != nil {
+				return fmt.Errorf("failed to add %s repository: %w", repo.Name, err)
+			}
+		}
+
+		if err := zypperRefresh(aliases...); err != nil {
+			return err
+		}
+
+		names := recipeNames(native)
+		args := append([]string{"--non-interactive", "install", "--auto-agree-with-licenses"}, names...)
+		cmd := sudoCommand("zypper", args...)
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+
+		fmt.Fprintf(stdout, "Running: zypper --non-interactive install --auto-agree-with-licenses %v\n", names)
+		if err := runCommand(cmd); err != nil {
+			return fmt.Errorf("failed to install %v: %w", names, err)
+		}
+
+		// openSUSE does not start services on install
+		for _, service := range recipeServices(native) {
+			if err := enableSystemdService(service); err != nil {
+				return err
+			}
+		}
+	}
+
+	return installOther(other)
+}
+
+// zypperRefresh refreshes the given repositories, or all of them when none are given.
+// Keys are never auto-imported; vendor keys are imported with rpm --import when their repository is added.
+func zypperRefresh(aliases ...string) error {
+	cmd := sudoCommand("zypper", append([]string{"--non-interactive", "refresh"}, aliases...)...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	fmt.Fprintf(stdout, "Running: zypper refresh %v\n", aliases)
+	if err := runCommand(cmd); err != nil {
+		return fmt.Errorf("failed to refresh repositories: %w", err)
+	}
+	return nil
+}
+
+// addZypperRepo imports the repository key and adds the repository unless it is already configured
+func addZypperRepo(alias string, repo zypperRepo, vars *strings.Replacer) error {
+	path := "/etc/zypp/repos.d/" + alias + ".repo"
+	if _, err := files.Stat(path); err == nil {
+		return nil
+	}
+
+	fmt.Fprintf(stdout, "Setting up %s repository...\n", repo.Name)
+
+	if repo.Key != "" {
+		key := vars.Replace(repo.Key)
+		keyCmd := sudoCommand("rpm", "--import", key)
+		keyCmd.Stdout = stdout
+		keyCmd.Stderr = stderr
+		if err := runAction(Action{Kind: ActionFile, Summary: fmt.Sprintf("Import %s signing key %s", repo.Name, key)}, keyCmd); err != nil {
+			return fmt.Errorf("failed to import signing key: %w", err)
+		}
+	}
+
+	url := vars.Replace(repo.URL)
+	args := []string{"--non-interactive", "addrepo", url}
+	if !strings.HasSuffix(url, ".repo") {
+		// A base URL needs an alias; a .repo file brings its own
+		args = append(args, alias)
+	}
+	cmd := sudoCommand("zypper", args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return trackFile(path, func() error {
+		return runAction(Action{Kind: ActionRepo, Summary: "Add zypper repository " + url, Path: path}, cmd)
+	})
+}
+
+func (z *ZypperManager) IsInstalled(pkg string) bool {
+	r := resolve("zypper", pkg)
+	if r.Method != methodPackage {
+		return otherInstalled(r)
+	}
+	return rpmInstalled(r.Name)
+}
+
+func (z *ZypperManager) Version(pkg string) (string, error) {
+	r := resolve("zypper", pkg)
+	if r.Method != methodPackage {
+		return otherVersion(r)
+	}
+	return rpmVersion(r.Name)
+}
+
+func (z *ZypperManager) Upgrade(packages ...string) error {
+	native, other := resolveAll("zypper", packages)
+
+	if len(native) > 0 {
+		if err := zypperRefresh(); err != nil {
+			return err
+		}
+
+		names := recipeNames(native)
+		cmd := sudoCommand("zypper", append([]string{"--non-interactive", "update", "--auto-agree-with-licenses"}, names...)...)
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+
+		fmt.Fprintf(stdout, "Running: zypper --non-interactive update --auto-agree-with-licenses %v\n", names)
+		if err := runCommand(cmd); err != nil {
+			return fmt.Errorf("failed to upgrade %v: %w", names, err)
+		}
+	}
+
+	return installOther(other)
+}
+
+func (z *ZypperManager) Remove(packages ...string) error {
+	native, other := resolveAll("zypper", packages)
+
+	if len(native) > 0 {
+		names := recipeNames(native)
+		cmd := sudoCommand("zypper", append([]string{"--non-interactive", "remove"}, names...)...)
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+
+		fmt.Fprintf(stdout, "Running: zypper --non-interactive remove %v\n", names)
+		if err := runCommand(cmd); err != nil {
+			return fmt.Errorf("failed to remove %v: %w", names, err)
+		}
+	}
+
+	return removeOther(other)
+}
@@ -0,0 +1,52 @@
+package installer_test
+
+import (
+	"fmt"
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/whexy/wenxuan-dev-init/pkg/installer"
+)
+
+func TestZypperInstallAddsVendorRepositories(t *testing.T) {
+	f := newFakes(t, "zypper")
+	f.write(t, "/etc/os-release", "ID=\"opensuse-tumbleweed\"\nVERSION_ID=\"20240601\"\n")
+
+	if err := installer.NewZypperManager().Install("git", "gh", "1password-cli"); err != nil {
+		t.Fatal(err)
+	}
+
+	rpmArch := map[string]string{"amd64": "x86_64", "arm64": "aarch64", "arm": "armv7hl", "386": "i686"}[runtime.GOARCH]
+	if rpmArch == "" {
+		rpmArch = runtime.GOARCH
+	}
+	want := []string{
+		"sudo rpm --import https://cli.github.com/packages/githubcli-archive-keyring.asc",
+		"sudo zypper --non-interactive addrepo https://cli.github.com/packages/rpm/gh-cli.repo",
+		"sudo rpm --import https://downloads.1password.com/linux/keys/1password.asc",
+		fmt.Sprintf("sudo zypper --non-interactive addrepo https://downloads.1password.com/linux/rpm/stable/%s 1password", rpmArch),
+		"sudo zypper --non-interactive refresh gh-cli 1password",
+		"sudo zypper --non-interactive install --auto-agree-with-licenses git gh 1password-cli",
+	}
+	if got := f.runner.Commands(); !slices.Equal(got, want) {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+}
+
+func TestZypperInstallKeepsExistingRepositories(t *testing.T) {
+	f := newFakes(t, "zypper")
+	f.write(t, "/etc/zypp/repos.d/gh-cli.repo", "[gh-cli]\n")
+
+	if err := installer.NewZypperManager().Install("gh"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"sudo zypper --non-interactive refresh gh-cli",
+		"sudo zypper --non-interactive install --auto-agree-with-licenses gh",
+	}
+	if got := f.runner.Commands(); !slices.Equal(got, want) {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+}
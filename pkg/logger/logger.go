@@ -2,8 +2,12 @@ package logger
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"sync"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/whexy/wenxuan-dev-init/pkg/events"
 )
 
 var (
@@ -11,28 +15,108 @@ var (
 	errorStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000")).Bold(true)
 	infoStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#7D56F4")).Bold(true)
 	warningStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFA500")).Bold(true)
+
+	levelStyles = map[string]lipgloss.Style{
+		events.LevelSuccess: successStyle,
+		events.LevelError:   errorStyle,
+		events.LevelInfo:    infoStyle,
+		events.LevelWarning: warningStyle,
+		events.LevelStep:    infoStyle,
+	}
+
+	levelIcons = map[string]string{
+		events.LevelSuccess: "✅",
+		events.LevelError:   "❌",
+		events.LevelInfo:    "📋",
+		events.LevelWarning: "⚠️ ",
+	}
+
+	pretty = &prettySink{output: os.Stdout}
 )
 
+// The pretty printer is the default sink; SetOutput redirects or silences it
+func init() {
+	events.Subscribe(pretty)
+}
+
+// SetOutput redirects the pretty-printed output to w
+func SetOutput(w io.Writer) {
+	pretty.mu.Lock()
+	defer pretty.mu.Unlock()
+	pretty.output = w
+}
+
+// Output returns where the pretty-printed output goes, so a temporary redirect can be undone
+func Output() io.Writer {
+	pretty.mu.Lock()
+	defer pretty.mu.Unlock()
+	return pretty.output
+}
+
 func Success(message string) {
-	fmt.Println(successStyle.Render("✅ " + message))
+	publish(events.LevelSuccess, "", message)
 }
 
 func Error(message string) {
-	fmt.Println(errorStyle.Render("❌ " + message))
+	publish(events.LevelError, "", message)
 }
 
 func Info(message string) {
-	fmt.Println(infoStyle.Render("📋 " + message))
+	publish(events.LevelInfo, "", message)
 }
 
 func Warning(message string) {
-	fmt.Println(warningStyle.Render("⚠️  " + message))
+	publish(events.LevelWarning, "", message)
 }
 
 func Step(icon, message string) {
-	fmt.Println(infoStyle.Render(icon + " " + message))
+	publish(events.LevelStep, icon, message)
 }
 
 func Println(message string) {
-	fmt.Println(message)
+	publish(events.LevelPlain, "", message)
+}
+
+func publish(level, icon, message string) {
+	events.Publish(events.Event{Kind: events.OutputLine, Stream: events.StreamLog, Level: level, Icon: icon, Line: message})
+}
+
+// Text renders an OutputLine event as plain text, prefixed with the icon of its level
+func Text(ev events.Event) string {
+	icon := ev.Icon
+	if icon == "" {
+		icon = levelIcons[ev.Level]
+	}
+	if icon == "" {
+		return ev.Line
+	}
+	return icon + " " + ev.Line
+}
+
+// prettySink prints log lines and step headers the way the logger always has: styled, with emoji.
+// Subprocess output is printed as is.
+type prettySink struct {
+	mu     sync.Mutex
+	output io.Writer
+}
+
+func (s *prettySink) Handle(ev events.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch ev.Kind {
+	case events.OutputLine:
+		s.println(ev.Level, Text(ev))
+
+	case events.StepStarted:
+		s.println(events.LevelPlain, "")
+		s.println(events.LevelStep, ev.Icon+" "+ev.Title+"...")
+	}
+}
+
+func (s *prettySink) println(level, text string) {
+	if style, ok := levelStyles[level]; ok {
+		text = style.Render(text)
+	}
+	fmt.Fprintln(s.output, text)
 }
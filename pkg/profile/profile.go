@@ -0,0 +1,200 @@
+package profile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	DefaultGitHubTokenRef      = "op://Developer/GitHub Personal Access Token/token"
+	DefaultTailscaleAuthKeyRef = "op://Developer/tailscale auth key/credential"
+	DefaultDotfilesRepo        = "whexy"
+)
+
+// PackageManagers are the system package managers a profile may pick, the same ones the installer supports
+var PackageManagers = []string{"apt", "pacman", "dnf", "yum", "zypper", "apk", "brew"}
+
+// toolOptions maps tool names in a profile to the option keys used by the TUI and executor
+var toolOptions = map[string]string{
+	"devbox":    "install_devbox",
+	"git":       "install_git",
+	"gh":        "install_gh",
+	"1password": "install_1password",
+	"chezmoi":   "install_chezmoi",
+	"tailscale": "install_tailscale",
+}
+
+// authOptions maps auth step names in a profile to option keys
+var authOptions = map[string]string{
+	"1password": "login_1password",
+	"github":    "setup_github",
+	"tailscale": "setup_tailscale",
+}
+
+// Profile declares what a machine should look like after setup
+type Profile struct {
+	// Tools selects which tools to install, keyed by tool name
+	Tools map[string]bool `yaml:"tools"`
+	// Packages lists extra packages installed alongside the tools
+	Packages []string `yaml:"packages"`
+	// PackageManager selects the system package manager, for example brew on Linux; empty detects it
+	PackageManager string `yaml:"package_manager"`
+	// Auth selects which authentication steps to run
+	Auth     map[string]bool `yaml:"auth"`
+	Dotfiles Dotfiles        `yaml:"dotfiles"`
+	Secrets  Secrets         `yaml:"secrets"`
+}
+
+// Dotfiles configures chezmoi initialization
+type Dotfiles struct {
+	Init *bool  `yaml:"init"`
+	Repo string `yaml:"repo"`
+}
+
+// Secrets holds 1Password references used during setup
+type Secrets struct {
+	GitHubToken      string `yaml:"github_token"`
+	TailscaleAuthKey string `yaml:"tailscale_authkey"`
+	ServiceAccount   bool   `yaml:"service_account"`
+}
+
+// Default returns a profile that only carries the built-in defaults
+func Default() *Profile {
+	p := &Profile{}
+	p.applyDefaults()
+	return p
+}
+
+// DefaultPath returns the default profile location
+func DefaultPath() string {
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		configHome = filepath.Join(os.Getenv("HOME"), ".config")
+	}
+	return filepath.Join(configHome, "wenxuan-dev-init", "profile.yaml")
+}
+
+// Load reads and validates the profile at path.
+// If path is empty, the default location is used and a missing file yields the default profile.
+func Load(path string) (*Profile, error) {
+	explicit := path != ""
+	if !explicit {
+		path = DefaultPath()
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			return Default(), nil
+		}
+		return nil, fmt.Errorf("failed to read profile: %w", err)
+	}
+
+	p, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid profile %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Parse decodes and validates a YAML profile
+func Parse(data []byte) (*Profile, error) {
+	p := &Profile{}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(p); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	p.applyDefaults()
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *Profile) applyDefaults() {
+	if p.Dotfiles.Repo == "" {
+		p.Dotfiles.Repo = DefaultDotfilesRepo
+	}
+	if p.Secrets.GitHubToken == "" {
+		p.Secrets.GitHubToken = DefaultGitHubTokenRef
+	}
+	if p.Secrets.TailscaleAuthKey == "" {
+		p.Secrets.TailscaleAuthKey = DefaultTailscaleAuthKeyRef
+	}
+}
+
+// Validate checks the profile for unknown names and malformed references
+func (p *Profile) Validate() error {
+	var problems []string
+
+	for name := range p.Tools {
+		if _, ok := toolOptions[name]; !ok {
+			problems = append(problems, fmt.Sprintf("unknown tool %q (known: %s)", name, knownNames(toolOptions)))
+		}
+	}
+	for name := range p.Auth {
+		if _, ok := authOptions[name]; !ok {
+			problems = append(problems, fmt.Sprintf("unknown auth step %q (known: %s)", name, knownNames(authOptions)))
+		}
+	}
+	for _, pkg := range p.Packages {
+		if strings.TrimSpace(pkg) == "" || strings.ContainsAny(pkg, " \t\n") {
+			problems = append(problems, fmt.Sprintf("invalid package name %q", pkg))
+		}
+	}
+	if p.PackageManager != "" && !slices.Contains(PackageManagers, p.PackageManager) {
+		problems = append(problems, fmt.Sprintf("unknown package manager %q (known: %s)", p.PackageManager, strings.Join(PackageManagers, ", ")))
+	}
+	if strings.ContainsAny(p.Dotfiles.Repo, " \t\n") {
+		problems = append(problems, fmt.Sprintf("invalid dotfiles repo %q", p.Dotfiles.Repo))
+	}
+	if !strings.HasPrefix(p.Secrets.GitHubToken, "op://") {
+		problems = append(problems, fmt.Sprintf("secrets.github_token must be an op:// reference, got %q", p.Secrets.GitHubToken))
+	}
+	if !strings.HasPrefix(p.Secrets.TailscaleAuthKey, "op://") {
+		problems = append(problems, fmt.Sprintf("secrets.tailscale_authkey must be an op:// reference, got %q", p.Secrets.TailscaleAuthKey))
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Options returns the option keys the profile declares, in the same form as the TUI selections.
+// Options the profile does not mention are absent so callers can fall back to their own defaults.
+func (p *Profile) Options() map[string]bool {
+	options := make(map[string]bool)
+	for name, enabled := range p.Tools {
+		options[toolOptions[name]] = enabled
+	}
+	for name, enabled := range p.Auth {
+		options[authOptions[name]] = enabled
+	}
+	if p.Dotfiles.Init != nil {
+		options["init_chezmoi"] = *p.Dotfiles.Init
+	}
+	return options
+}
+
+func knownNames(m map[string]string) string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
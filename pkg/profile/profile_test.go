@@ -0,0 +1,98 @@
+package profile
+
+import (
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	data := []byte(`
+tools:
+  devbox: false
+  gh: true
+packages: [ripgrep, fd]
+package_manager: brew
+auth:
+  tailscale: false
+dotfiles:
+  init: false
+  repo: octocat/dotfiles
+secrets:
+  github_token: op://Private/gh/token
+  service_account: true
+`)
+	p, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.PackageManager != "brew" || p.Dotfiles.Repo != "octocat/dotfiles" || !p.Secrets.ServiceAccount {
+		t.Errorf("Parse() = %+v", p)
+	}
+	if p.Secrets.GitHubToken != "op://Private/gh/token" || p.Secrets.TailscaleAuthKey != DefaultTailscaleAuthKeyRef {
+		t.Errorf("secrets = %+v, want the declared GitHub token and the default Tailscale key", p.Secrets)
+	}
+
+	want := map[string]bool{"install_devbox": false, "install_gh": true, "setup_tailscale": false, "init_chezmoi": false}
+	if got := p.Options(); !maps.Equal(got, want) {
+		t.Errorf("Options() = %v, want %v", got, want)
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	p, err := Parse(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Dotfiles.Repo != DefaultDotfilesRepo || p.Secrets.GitHubToken != DefaultGitHubTokenRef {
+		t.Errorf("Parse(nil) = %+v, want the defaults", p)
+	}
+	if len(p.Options()) != 0 {
+		t.Errorf("Options() = %v, want none", p.Options())
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"unknown key", "toolz: {git: true}", "field toolz not found"},
+		{"unknown nested key", "dotfiles: {branch: main}", "field branch not found"},
+		{"unknown tool", "tools: {vim: true}", `unknown tool "vim"`},
+		{"unknown auth step", "auth: {gitlab: true}", `unknown auth step "gitlab"`},
+		{"unknown package manager", "package_manager: portage", `unknown package manager "portage" (known: apt, pacman, dnf, yum, zypper, apk, brew)`},
+		{"package with spaces", "packages: [\"ripgrep fd\"]", `invalid package name "ripgrep fd"`},
+		{"empty package", "packages: [\"\"]", `invalid package name ""`},
+		{"dotfiles repo with spaces", "dotfiles: {repo: a b}", `invalid dotfiles repo "a b"`},
+		{"secret that is not a reference", "secrets: {github_token: ghp_123}", "secrets.github_token must be an op:// reference"},
+		{"wrong type", "tools: [git]", "cannot unmarshal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.yaml))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse(%q) error = %v, want it to contain %q", tt.yaml, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	p := Default()
+	p.Tools = map[string]bool{"vim": true}
+	p.PackageManager = "portage"
+
+	err := p.Validate()
+	if err == nil {
+		t.Fatal("Validate() = nil, want an error")
+	}
+	for _, want := range []string{`unknown tool "vim"`, `unknown package manager "portage"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() = %v, want it to mention %s", err, want)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// Services of the logins recorded in the manifest
+const (
+	LoginGitHub    = "github"
+	LoginTailscale = "tailscale"
+	Login1Password = "1password"
+)
+
+// Manifest records every change setup made on this machine, across runs, so support can see what happened
+// and uninstall can undo exactly that
+type Manifest struct {
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+	// Files were created or modified by setup: repository definitions, signing keys, config and shell rc files
+	Files []File `json:"files,omitempty"`
+	// Packages were installed by setup; packages that were already installed are not recorded
+	Packages []Package `json:"packages,omitempty"`
+	// Logins are the accounts setup signed in to
+	Logins []Login `json:"logins,omitempty"`
+
+	path string
+}
+
+// File is a file setup created or modified
+type File struct {
+	Path string `json:"path"`
+	// Created is false for files that existed before setup changed them; uninstall only deletes files setup created
+	Created bool `json:"created"`
+	// Append is the text setup added to the end of an existing file, when that is all it changed
+	Append string `json:"append,omitempty"`
+	// SHA256 is the hash of the content setup left behind, empty when the file could not be read
+	SHA256 string    `json:"sha256,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// Package is a package setup installed
+type Package struct {
+	Name    string `json:"name"`
+	Manager string `json:"manager"`
+	Version string `json:"version,omitempty"`
+	// Path is the binary of a tool that was installed by its vendor's installer rather than the package manager
+	Path string    `json:"path,omitempty"`
+	Time time.Time `json:"time"`
+}
+
+// Login is an account setup signed in to
+type Login struct {
+	Service string `json:"service"`
+	// Account identifies the account: the host for GitHub, the user ID for 1Password
+	Account string    `json:"account,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// ManifestPath returns the location of the manifest file
+func ManifestPath() string {
+	return filepath.Join(Dir(), "manifest.json")
+}
+
+// LoadManifest reads the manifest; it returns an empty manifest when setup has not changed anything yet
+func LoadManifest() (*Manifest, error) {
+	path := ManifestPath()
+	m := &Manifest{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// IsEmpty reports whether setup has no changes on record
+func (m *Manifest) IsEmpty() bool {
+	return len(m.Files) == 0 && len(m.Packages) == 0 && len(m.Logins) == 0
+}
+
+// AddFile records a change to a file. A file keeps the Created flag of its first record,
+// and it is only an append while every change to it was one.
+func (m *Manifest) AddFile(f File) {
+	i := slices.IndexFunc(m.Files, func(old File) bool { return old.Path == f.Path })
+	if i < 0 {
+		m.Files = append(m.Files, f)
+		return
+	}
+
+	old := m.Files[i]
+	f.Created = old.Created
+	if old.Created || old.Append == "" || f.Append == "" {
+		f.Append = ""
+	} else {
+		f.Append = old.Append + f.Append
+	}
+	m.Files[i] = f
+}
+
+// RemoveFile forgets a file
+func (m *Manifest) RemoveFile(path string) {
+	m.Files = slices.DeleteFunc(m.Files, func(f File) bool { return f.Path == path })
+}
+
+// AddPackage records a package, replacing an earlier record of it
+func (m *Manifest) AddPackage(p Package) {
+	m.RemovePackage(p.Manager, p.Name)
+	m.Packages = append(m.Packages, p)
+}
+
+// RemovePackage forgets a package
+func (m *Manifest) RemovePackage(manager, name string) {
+	m.Packages = slices.DeleteFunc(m.Packages, func(p Package) bool { return p.Manager == manager && p.Name == name })
+}
+
+// PackagesByManager returns the names of the recorded packages of each package manager
+func (m *Manifest) PackagesByManager() map[string][]string {
+	packages := make(map[string][]string)
+	for _, p := range m.Packages {
+		packages[p.Manager] = append(packages[p.Manager], p.Name)
+	}
+	return packages
+}
+
+// AddLogin records an account, replacing an earlier record of it
+func (m *Manifest) AddLogin(l Login) {
+	m.RemoveLogin(l)
+	m.Logins = append(m.Logins, l)
+}
+
+// RemoveLogin forgets an account
+func (m *Manifest) RemoveLogin(l Login) {
+	m.Logins = slices.DeleteFunc(m.Logins, func(old Login) bool { return old.Service == l.Service && old.Account == l.Account })
+}
+
+// Save writes the manifest atomically, or removes the file once there is nothing left on record
+func (m *Manifest) Save() error {
+	if m.IsEmpty() {
+		if err := os.Remove(m.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove manifest: %w", err)
+		}
+		return nil
+	}
+
+	m.Updated = time.Now()
+	if m.Created.IsZero() {
+		m.Created = m.Updated
+	}
+	if err := writeJSON(m.path, m); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
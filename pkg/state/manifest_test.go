@@ -0,0 +1,144 @@
+package state_test
+
+import (
+	"os"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/whexy/wenxuan-dev-init/pkg/state"
+)
+
+func TestManifestAddFile(t *testing.T) {
+	const shellenv = "eval \"$(devbox global shellenv)\"\n"
+	tests := []struct {
+		name    string
+		records []state.File
+		want    state.File
+	}{
+		{
+			name: "created then rewritten",
+			records: []state.File{
+				{Path: "/etc/yum.repos.d/gh-cli.repo", Created: true, SHA256: "first"},
+				{Path: "/etc/yum.repos.d/gh-cli.repo", Created: false, SHA256: "second"},
+			},
+			// The second run finds the file it created itself; uninstall compares against the content it last wrote
+			want: state.File{Path: "/etc/yum.repos.d/gh-cli.repo", Created: true, SHA256: "second"},
+		},
+		{
+			name: "pre-existing file rewritten",
+			records: []state.File{
+				{Path: "/home/wx/.gitconfig", Created: false, SHA256: "first"},
+				{Path: "/home/wx/.gitconfig", Created: true, SHA256: "second"},
+			},
+			want: state.File{Path: "/home/wx/.gitconfig", Created: false, SHA256: "second"},
+		},
+		{
+			name: "appended across runs",
+			records: []state.File{
+				{Path: "/home/wx/.bashrc", Append: shellenv, SHA256: "first"},
+				{Path: "/home/wx/.bashrc", Append: "export EDITOR=vim\n", SHA256: "second"},
+			},
+			want: state.File{Path: "/home/wx/.bashrc", Append: shellenv + "export EDITOR=vim\n", SHA256: "second"},
+		},
+		{
+			name: "appended then rewritten",
+			records: []state.File{
+				{Path: "/home/wx/.bashrc", Append: shellenv, SHA256: "first"},
+				{Path: "/home/wx/.bashrc", SHA256: "second"},
+				{Path: "/home/wx/.bashrc", Append: shellenv, SHA256: "third"},
+			},
+			// Once setup changed more than the end of the file, uninstall can no longer take its changes out
+			want: state.File{Path: "/home/wx/.bashrc", SHA256: "third"},
+		},
+		{
+			name: "created then appended",
+			records: []state.File{
+				{Path: "/home/wx/.profile", Created: true, SHA256: "first"},
+				{Path: "/home/wx/.profile", Append: shellenv, SHA256: "second"},
+			},
+			// A file setup created is deleted whole
+			want: state.File{Path: "/home/wx/.profile", Created: true, SHA256: "second"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m state.Manifest
+			for _, f := range tt.records {
+				m.AddFile(f)
+			}
+			if len(m.Files) != 1 {
+				t.Fatalf("Files = %+v, want one record", m.Files)
+			}
+			if !reflect.DeepEqual(m.Files[0], tt.want) {
+				t.Errorf("file = %+v, want %+v", m.Files[0], tt.want)
+			}
+		})
+	}
+}
+
+func TestManifestSaveLoad(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	m, err := state.LoadManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.IsEmpty() {
+		t.Fatalf("LoadManifest() without a file = %+v, want an empty manifest", m)
+	}
+
+	at := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	m.AddFile(state.File{Path: "/etc/apt/sources.list.d/github-cli.list", Created: true, SHA256: "abc", Time: at})
+	m.AddFile(state.File{Path: "/home/wx/.bashrc", Append: "export EDITOR=vim\n", Time: at})
+	m.AddPackage(state.Package{Name: "gh", Manager: "apt", Version: "2.62.0", Time: at})
+	m.AddPackage(state.Package{Name: "chezmoi", Manager: "vendor", Path: "/usr/local/bin/chezmoi", Time: at})
+	m.AddLogin(state.Login{Service: state.LoginGitHub, Account: "github.com", Time: at})
+	if err := m.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := state.LoadManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Created.Equal(m.Created) || !loaded.Updated.Equal(m.Updated) {
+		t.Errorf("times = %v, %v, want %v, %v", loaded.Created, loaded.Updated, m.Created, m.Updated)
+	}
+	if !reflect.DeepEqual(loaded.Files, m.Files) || !reflect.DeepEqual(loaded.Packages, m.Packages) || !reflect.DeepEqual(loaded.Logins, m.Logins) {
+		t.Errorf("LoadManifest() = %+v, want %+v", loaded, m)
+	}
+
+	// Forgetting everything removes the file
+	for _, f := range slices.Clone(loaded.Files) {
+		loaded.RemoveFile(f.Path)
+	}
+	for _, p := range slices.Clone(loaded.Packages) {
+		loaded.RemovePackage(p.Manager, p.Name)
+	}
+	for _, l := range slices.Clone(loaded.Logins) {
+		loaded.RemoveLogin(l)
+	}
+	if err := loaded.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(state.ManifestPath()); !os.IsNotExist(err) {
+		t.Errorf("%s still exists after everything was forgotten", state.ManifestPath())
+	}
+}
+
+func TestLoadManifestRejectsCorruptFile(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	if err := os.MkdirAll(state.Dir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(state.ManifestPath(), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := state.LoadManifest(); err == nil {
+		t.Error("LoadManifest() of a truncated file succeeded")
+	}
+}
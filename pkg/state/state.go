@@ -0,0 +1,121 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// State is what a run persists so it can be resumed after a shell reload, a crash or a reboot
+type State struct {
+	// Options are the confirmed selections
+	Options map[string]bool `json:"options"`
+	// Completed lists the IDs of steps that succeeded or were already done
+	Completed []string `json:"completed"`
+	// Current is the step that was running when the state was last saved
+	Current string    `json:"current,omitempty"`
+	Started time.Time `json:"started"`
+	Updated time.Time `json:"updated"`
+
+	path string
+}
+
+// Dir returns the state directory: $XDG_STATE_HOME/wenxuan-dev-init, or ~/.local/state/wenxuan-dev-init
+func Dir() string {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "wenxuan-dev-init")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".local", "state", "wenxuan-dev-init")
+}
+
+// Path returns the location of the run state file
+func Path() string {
+	return filepath.Join(Dir(), "run.json")
+}
+
+// New starts the state of a run with the confirmed selections
+func New(options map[string]bool) *State {
+	now := time.Now()
+	return &State{Options: options, Completed: []string{}, Started: now, Updated: now, path: Path()}
+}
+
+// Load reads the state of an unfinished run; it returns nil without an error when there is none
+func Load() (*State, error) {
+	path := Path()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run state: %w", err)
+	}
+
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse run state %s: %w", path, err)
+	}
+	s.path = path
+	return &s, nil
+}
+
+// Clear removes the state file once a run has finished
+func Clear() error {
+	if err := os.Remove(Path()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove run state: %w", err)
+	}
+	return nil
+}
+
+// IsCompleted reports whether the step finished in an earlier run
+func (s *State) IsCompleted(id string) bool {
+	return slices.Contains(s.Completed, id)
+}
+
+// Begin records that a step started and saves the state
+func (s *State) Begin(id string) error {
+	s.Current = id
+	return s.Save()
+}
+
+// Finish records that a step ended, and whether it completed, and saves the state
+func (s *State) Finish(id string, completed bool) error {
+	if completed && !s.IsCompleted(id) {
+		s.Completed = append(s.Completed, id)
+	}
+	s.Current = ""
+	return s.Save()
+}
+
+// Save writes the state atomically so an interrupted write never leaves a broken file
+func (s *State) Save() error {
+	s.Updated = time.Now()
+	if err := writeJSON(s.path, s); err != nil {
+		return fmt.Errorf("failed to write run state: %w", err)
+	}
+	return nil
+}
+
+// writeJSON writes v to path through a temporary file and a rename
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
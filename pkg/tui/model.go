@@ -1,13 +1,18 @@
 package tui
 
 import (
+	"context"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/whexy/wenxuan-dev-init/pkg/installer"
+	"github.com/whexy/wenxuan-dev-init/pkg/profile"
 )
 
 type Dependency struct {
@@ -31,6 +36,20 @@ type Model struct {
 	confirmed    bool
 	width        int
 	height       int
+
+	// Progress of a run started with WithRun
+	run         RunFunc
+	cancel      context.CancelFunc
+	phase       phase
+	events      chan tea.Msg
+	steps       []*stepView
+	stepCursor  int
+	expanded    map[string]bool
+	spinner     spinner.Model
+	started     time.Time
+	runErr      error
+	interrupted bool
+	resume      bool
 }
 
 type keyMap struct {
@@ -40,6 +59,7 @@ type keyMap struct {
 	Enter  key.Binding
 	Quit   key.Binding
 	Help   key.Binding
+	Expand key.Binding
 }
 
 var keys = keyMap{
@@ -67,40 +87,78 @@ var keys = keyMap{
 		key.WithKeys("?"),
 		key.WithHelp("?", "help"),
 	),
+	Expand: key.NewBinding(
+		key.WithKeys("tab"),
+		key.WithHelp("tab", "expand output"),
+	),
+}
+
+// Host is what the model knows about the machine it sets up
+type Host struct {
+	// Commands lists the commands found on PATH
+	Commands map[string]bool
+	// PackageManager is the detected package manager, or "" when there is none
+	PackageManager string
+	InContainer    bool
+	TailscaleSetup bool
+}
+
+// hostCommands are the commands the dependency box reports on
+var hostCommands = []string{"git", "gh", "op", "chezmoi", "devbox", "tailscale"}
+
+// DetectHost inspects the running machine
+func DetectHost() Host {
+	host := Host{Commands: make(map[string]bool), InContainer: installer.IsRunningInContainer()}
+	for _, cmd := range hostCommands {
+		host.Commands[cmd] = installer.IsCommandAvailable(cmd)
+	}
+	if pkgMgr, err := installer.DetectPackageManager(); err == nil {
+		host.PackageManager = pkgMgr.Name()
+	}
+	host.TailscaleSetup = host.Commands["tailscale"] && installer.IsTailscaleSetup()
+	return host
 }
 
-func NewModel() Model {
+// NewModel builds the model from host detection, with selections declared in prof taking precedence
+func NewModel(prof *profile.Profile) Model {
+	return NewModelFor(DetectHost(), prof)
+}
+
+// NewModelFor builds the model for host, with selections declared in prof taking precedence
+func NewModelFor(host Host, prof *profile.Profile) Model {
+	if prof == nil {
+		prof = profile.Default()
+	}
+	has := func(cmd string) bool { return host.Commands[cmd] }
+
 	// Check dependencies
 	deps := []Dependency{
-		{Name: "Git", Command: "git", Available: installer.IsCommandAvailable("git"), Icon: "🔧"},
-		{Name: "GitHub CLI", Command: "gh", Available: installer.IsCommandAvailable("gh"), Icon: "🐙"},
-		{Name: "1Password CLI", Command: "op", Available: installer.IsCommandAvailable("op"), Icon: "🔐"},
-		{Name: "Chezmoi", Command: "chezmoi", Available: installer.IsCommandAvailable("chezmoi"), Icon: "🏠"},
-		{Name: "Devbox", Command: "devbox", Available: installer.IsCommandAvailable("devbox"), Icon: "📦"},
-		{Name: "Tailscale", Command: "tailscale", Available: installer.IsCommandAvailable("tailscale"), Icon: "🔗"},
+		{Name: "Git", Command: "git", Available: has("git"), Icon: "🔧"},
+		{Name: "GitHub CLI", Command: "gh", Available: has("gh"), Icon: "🐙"},
+		{Name: "1Password CLI", Command: "op", Available: has("op"), Icon: "🔐"},
+		{Name: "Chezmoi", Command: "chezmoi", Available: has("chezmoi"), Icon: "🏠"},
+		{Name: "Devbox", Command: "devbox", Available: has("devbox"), Icon: "📦"},
+		{Name: "Tailscale", Command: "tailscale", Available: has("tailscale"), Icon: "🔗"},
 	}
 
-	// Detect package manager
-	pkgMgrAvailable := false
-	pkgMgrName := "none"
-	if pkgMgr, err := installer.DetectPackageManager(); err == nil {
-		pkgMgrAvailable = true
-		pkgMgrName = pkgMgr.Name()
+	// Package manager
+	pkgMgrName := host.PackageManager
+	if pkgMgrName == "" {
+		pkgMgrName = "none"
 	}
 
 	deps = append(deps, Dependency{
 		Name:      fmt.Sprintf("Package Manager (%s)", pkgMgrName),
 		Command:   pkgMgrName,
-		Available: pkgMgrAvailable,
+		Available: host.PackageManager != "",
 		Icon:      "📦",
 	})
 
 	// Configuration options
-	inContainer := installer.IsRunningInContainer()
 	devboxDescription := "Install devbox package manager (recommended for Linux)"
-	devboxEnabled := !installer.IsCommandAvailable("devbox")
+	devboxEnabled := !has("devbox")
 
-	if inContainer {
+	if host.InContainer {
 		devboxDescription = "⚠️  NOT recommended in containers (requires Nix daemon)"
 		devboxEnabled = false // Disable by default in containers
 	}
@@ -115,31 +173,31 @@ func NewModel() Model {
 		{
 			Label:       "Install Git",
 			Description: "Install git version control system",
-			Enabled:     !installer.IsCommandAvailable("git"),
+			Enabled:     !has("git"),
 			Key:         "install_git",
 		},
 		{
 			Label:       "Install GitHub CLI",
 			Description: "Install gh command-line tool",
-			Enabled:     !installer.IsCommandAvailable("gh"),
+			Enabled:     !has("gh"),
 			Key:         "install_gh",
 		},
 		{
 			Label:       "Install 1Password CLI",
 			Description: "Install 1Password command-line tool",
-			Enabled:     !installer.IsCommandAvailable("op"),
+			Enabled:     !has("op"),
 			Key:         "install_1password",
 		},
 		{
 			Label:       "Install Chezmoi",
 			Description: "Install chezmoi dotfile manager",
-			Enabled:     !installer.IsCommandAvailable("chezmoi"),
+			Enabled:     !has("chezmoi"),
 			Key:         "install_chezmoi",
 		},
 		{
 			Label:       "Install Tailscale",
 			Description: "Install Tailscale VPN client",
-			Enabled:     !installer.IsCommandAvailable("tailscale"),
+			Enabled:     !has("tailscale"),
 			Key:         "install_tailscale",
 		},
 		{
@@ -156,27 +214,41 @@ func NewModel() Model {
 		},
 		{
 			Label:       "Initialize Chezmoi",
-			Description: "Run chezmoi init --apply whexy",
+			Description: fmt.Sprintf("Run chezmoi init --apply %s", prof.Dotfiles.Repo),
 			Enabled:     true,
 			Key:         "init_chezmoi",
 		},
 		{
 			Label:       "Setup Tailscale",
 			Description: "Configure and connect to Tailscale network",
-			Enabled:     installer.IsCommandAvailable("tailscale") && !installer.IsTailscaleSetup(),
+			Enabled:     has("tailscale") && !host.TailscaleSetup,
 			Key:         "setup_tailscale",
 		},
 	}
 
+	// Profile selections override detected defaults
+	declared := prof.Options()
+	for i := range options {
+		if enabled, ok := declared[options[i].Key]; ok {
+			options[i].Enabled = enabled
+		}
+	}
+
+	return NewModelWith(deps, options)
+}
+
+// NewModelWith builds the model from the dependencies and options to show, without looking at the host
+func NewModelWith(deps []Dependency, options []ConfigOption) Model {
 	return Model{
-		dependencies: deps,
-		options:      options,
-		cursor:       0,
-		confirmed:    false,
+		dependencies: slices.Clone(deps),
+		options:      slices.Clone(options),
 	}
 }
 
 func (m Model) Init() tea.Cmd {
+	if m.resume && m.run != nil {
+		return func() tea.Msg { return startMsg{} }
+	}
 	return nil
 }
 
@@ -186,6 +258,15 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.width = msg.Width
 		m.height = msg.Height
 		return m, nil
+	}
+
+	if m.phase != phaseSelect {
+		return m.updateProgress(msg)
+	}
+
+	switch msg := msg.(type) {
+	case startMsg:
+		return m.startRun()
 
 	case tea.KeyMsg:
 		switch {
@@ -209,6 +290,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		case key.Matches(msg, keys.Enter):
 			m.confirmed = true
+			if m.run != nil {
+				return m.startRun()
+			}
 			return m, tea.Quit
 		}
 	}
@@ -217,6 +301,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m Model) View() string {
+	if m.phase != phaseSelect {
+		return m.progressView()
+	}
 	if m.confirmed {
 		return ""
 	}
@@ -0,0 +1,108 @@
+package tui_test
+
+import (
+	"flag"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"github.com/whexy/wenxuan-dev-init/pkg/profile"
+	"github.com/whexy/wenxuan-dev-init/pkg/tui"
+	"github.com/whexy/wenxuan-dev-init/pkg/tui/tuitest"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestMain(m *testing.M) {
+	// Plain text snapshots, whatever terminal runs the tests
+	lipgloss.SetColorProfile(termenv.Ascii)
+	os.Exit(m.Run())
+}
+
+var (
+	freshHost = tui.Host{Commands: map[string]bool{}, PackageManager: "apt"}
+	readyHost = tui.Host{
+		Commands:       map[string]bool{"git": true, "gh": true, "op": true, "chezmoi": true, "devbox": true, "tailscale": true},
+		PackageManager: "devbox",
+		TailscaleSetup: true,
+	}
+	containerHost = tui.Host{Commands: map[string]bool{"git": true}, InContainer: true}
+)
+
+func TestModelView(t *testing.T) {
+	tests := []struct {
+		name string
+		host tui.Host
+		prof *profile.Profile
+		keys []string
+	}{
+		{name: "fresh", host: freshHost},
+		{name: "ready", host: readyHost},
+		{name: "container", host: containerHost},
+		{name: "profile", host: freshHost, prof: &profile.Profile{
+			Tools:    map[string]bool{"devbox": false, "tailscale": false},
+			Auth:     map[string]bool{"github": false},
+			Dotfiles: profile.Dotfiles{Repo: "octocat/dotfiles"},
+		}},
+		// Moving shows the description of the option under the cursor; space toggles it
+		{name: "toggle", host: freshHost, keys: []string{"down", "down", "space", "j", " "}},
+		// The cursor stops at both ends of the list
+		{name: "top", host: freshHost, keys: []string{"up", "k"}},
+		{name: "bottom", host: readyHost, keys: repeat("down", 12)},
+		{name: "back-up", host: readyHost, keys: append(repeat("j", 9), "space", "k", "k")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := tuitest.NewDriver(tui.NewModelFor(tt.host, tt.prof)).Resize(80, 40).Press(tt.keys...)
+			tuitest.AssertGolden(t, filepath.Join("testdata", tt.name+".golden"), d.View(), *update)
+		})
+	}
+}
+
+func TestModelConfirm(t *testing.T) {
+	d := tuitest.NewDriver(tui.NewModelFor(freshHost, nil)).Press("down", "space", "enter")
+
+	m := d.Model().(tui.Model)
+	if !m.IsConfirmed() {
+		t.Fatal("enter did not confirm the selection")
+	}
+	if view := d.View(); view != "" {
+		t.Errorf("View() after confirming = %q, want it empty", view)
+	}
+	if len(d.Cmds()) != 1 {
+		t.Errorf("Update returned %d commands, want the quit command only", len(d.Cmds()))
+	}
+
+	want := map[string]bool{
+		"install_devbox": true, "install_git": false, "install_gh": true, "install_1password": true,
+		"install_chezmoi": true, "install_tailscale": true, "login_1password": true, "setup_github": true,
+		"init_chezmoi": true, "setup_tailscale": false,
+	}
+	if got := m.GetSelectedOptions(); !maps.Equal(got, want) {
+		t.Errorf("GetSelectedOptions() = %v, want %v", got, want)
+	}
+}
+
+func TestModelQuit(t *testing.T) {
+	for _, k := range []string{"q", "ctrl+c"} {
+		d := tuitest.NewDriver(tui.NewModelFor(freshHost, nil)).Press(k)
+		if d.Model().(tui.Model).IsConfirmed() {
+			t.Errorf("%s confirmed the selection", k)
+		}
+		if len(d.Cmds()) != 1 {
+			t.Errorf("%s returned %d commands, want the quit command", k, len(d.Cmds()))
+		}
+	}
+}
+
+func repeat(key string, n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = key
+	}
+	return keys
+}
@@ -0,0 +1,351 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/whexy/wenxuan-dev-init/pkg/events"
+	"github.com/whexy/wenxuan-dev-init/pkg/executor"
+	"github.com/whexy/wenxuan-dev-init/pkg/logger"
+)
+
+// RunFunc executes the confirmed options, publishing progress to sink, and returns when the run ends.
+// ctx is cancelled when the user interrupts the run.
+type RunFunc func(ctx context.Context, options map[string]bool, sink events.Sink) error
+
+type phase int
+
+const (
+	phaseSelect phase = iota
+	phaseRunning
+	phaseDone
+)
+
+// collapsedLines is how many output lines a collapsed running step shows
+const collapsedLines = 1
+
+// stepView is the progress state of one step
+type stepView struct {
+	id       string
+	title    string
+	icon     string
+	started  time.Time
+	finished time.Time
+	output   []string
+	result   *executor.Result
+}
+
+type eventMsg events.Event
+
+type runDoneMsg struct {
+	err error
+}
+
+// startMsg starts a resumed run without going through the selection screen
+type startMsg struct{}
+
+// WithRun makes the model execute the selections itself after confirmation and show live progress.
+// Without it, confirming quits the program and the caller runs the selections.
+func (m Model) WithRun(run RunFunc) Model {
+	m.run = run
+	return m
+}
+
+// Resume preselects options from an earlier run and starts running them as soon as the program starts
+func (m Model) Resume(options map[string]bool) Model {
+	for i := range m.options {
+		m.options[i].Enabled = options[m.options[i].Key]
+	}
+	m.confirmed = true
+	m.resume = true
+	return m
+}
+
+// RunError returns the error the run finished with
+func (m Model) RunError() error {
+	return m.runErr
+}
+
+// Interrupted reports whether the user quit while the run was still going
+func (m Model) Interrupted() bool {
+	return m.interrupted
+}
+
+// Results returns the step results seen so far
+func (m Model) Results() []executor.Result {
+	var results []executor.Result
+	for _, s := range m.steps {
+		if s.result != nil {
+			results = append(results, *s.result)
+		}
+	}
+	return results
+}
+
+// startRun switches to the progress view and starts the run
+func (m Model) startRun() (Model, tea.Cmd) {
+	m.phase = phaseRunning
+	m.started = time.Now()
+	m.expanded = make(map[string]bool)
+	m.spinner = spinner.New(spinner.WithSpinner(spinner.Dot))
+
+	msgs := make(chan tea.Msg, 256)
+	m.events = msgs
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+
+	options := m.GetSelectedOptions()
+	run := m.run
+	execute := func() tea.Msg {
+		err := run(ctx, options, events.SinkFunc(func(ev events.Event) {
+			// Once the user quit, nothing reads the channel any more
+			select {
+			case msgs <- eventMsg(ev):
+			case <-ctx.Done():
+			}
+		}))
+		close(msgs)
+		return runDoneMsg{err: err}
+	}
+
+	return m, tea.Batch(m.spinner.Tick, execute, waitForEvent(msgs))
+}
+
+// waitForEvent delivers the next run event as a message
+func waitForEvent(events <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-events
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+func (m Model) updateProgress(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case eventMsg:
+		m.applyEvent(events.Event(msg))
+		return m, waitForEvent(m.events)
+
+	case runDoneMsg:
+		// Apply events that were still queued when the run returned
+		for ev := range m.events {
+			if ev, ok := ev.(eventMsg); ok {
+				m.applyEvent(events.Event(ev))
+			}
+		}
+		m.phase = phaseDone
+		m.runErr = msg.err
+		m.cancel()
+		return m, nil
+
+	case spinner.TickMsg:
+		if m.phase != phaseRunning {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+
+	case tea.KeyMsg:
+		switch {
+		case msg.String() == "ctrl+c":
+			m.interrupted = m.phase == phaseRunning
+			// Stops the commands that are still running
+			m.cancel()
+			return m, tea.Quit
+
+		case key.Matches(msg, keys.Quit), key.Matches(msg, keys.Enter):
+			if m.phase == phaseDone {
+				return m, tea.Quit
+			}
+
+		case key.Matches(msg, keys.Up):
+			if m.stepCursor > 0 {
+				m.stepCursor--
+			}
+
+		case key.Matches(msg, keys.Down):
+			if m.stepCursor < len(m.steps)-1 {
+				m.stepCursor++
+			}
+
+		case key.Matches(msg, keys.Space), key.Matches(msg, keys.Expand):
+			if m.stepCursor < len(m.steps) {
+				id := m.steps[m.stepCursor].id
+				m.expanded[id] = !m.expanded[id]
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// applyEvent folds a run event into the step views
+func (m *Model) applyEvent(ev events.Event) {
+	switch ev.Kind {
+	case events.StepStarted:
+		m.steps = append(m.steps, &stepView{id: ev.Step, title: ev.Title, icon: ev.Icon, started: ev.Time})
+		m.stepCursor = len(m.steps) - 1
+
+	case events.OutputLine:
+		m.appendOutput(ev.Step, logger.Text(ev))
+
+	case events.CommandStarted:
+		m.appendOutput(ev.Step, "$ "+ev.Command)
+
+	case events.PromptRequested:
+		m.appendOutput(ev.Step, "⌨️  "+ev.Prompt)
+
+	case events.StepFinished:
+		if s := m.findStep(ev.Step); s != nil {
+			res := executor.ResultFromEvent(ev)
+			s.result = &res
+			s.finished = ev.Time
+		}
+	}
+}
+
+func (m *Model) appendOutput(id, line string) {
+	if s := m.findStep(id); s != nil {
+		s.output = append(s.output, line)
+	}
+}
+
+func (m *Model) findStep(id string) *stepView {
+	for _, s := range m.steps {
+		if s.id == id {
+			return s
+		}
+	}
+	return nil
+}
+
+func (m Model) progressView() string {
+	var s strings.Builder
+
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("#7D56F4")).
+		Background(lipgloss.Color("#1a1a1a")).
+		Padding(0, 1).
+		MarginBottom(1)
+
+	headerStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("#FAFAFA")).
+		Background(lipgloss.Color("#7D56F4")).
+		Padding(0, 1)
+
+	successStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#04B575"))
+	errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000"))
+	warningStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FFA500"))
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#7D56F4")).Bold(true)
+	outputStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#888888"))
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#626262")).Padding(1, 0)
+
+	s.WriteString(titleStyle.Render("🚀 Wenxuan Dev Init - Setting Up"))
+	s.WriteString("\n\n")
+
+	header := fmt.Sprintf(" ⏱️  Running for %s ", formatDuration(time.Since(m.started)))
+	if m.phase == phaseDone {
+		header = " 📊 Results "
+	}
+	s.WriteString(headerStyle.Render(header))
+	s.WriteString("\n\n")
+
+	for i, step := range m.steps {
+		var status string
+		elapsed := time.Since(step.started)
+		switch {
+		case step.result == nil:
+			status = m.spinner.View()
+		case step.result.Status == executor.StatusSucceeded:
+			status = successStyle.Render("✓")
+		case step.result.Status == executor.StatusAlreadyDone:
+			status = successStyle.Render("=")
+		case step.result.Status == executor.StatusFailed:
+			status = errorStyle.Render("✗")
+		default:
+			status = warningStyle.Render("-")
+		}
+		if step.result != nil {
+			elapsed = step.finished.Sub(step.started)
+		}
+
+		line := fmt.Sprintf("%s %s %s  %s", status, step.icon, step.title, outputStyle.Render(formatDuration(elapsed)))
+		if i == m.stepCursor {
+			line = selectedStyle.Render("▶ ") + line
+		} else {
+			line = "  " + line
+		}
+		s.WriteString(line)
+		s.WriteString("\n")
+
+		if step.result != nil && step.result.Err != nil {
+			msg := strings.Split(step.result.Err.Error(), "\n")
+			s.WriteString("      " + warningStyle.Render(msg[0]) + "\n")
+			for _, l := range msg[1:] {
+				s.WriteString("      " + outputStyle.Render("│ "+l) + "\n")
+			}
+			if step.result.Hint != "" {
+				s.WriteString("      " + outputStyle.Render("→ "+step.result.Hint) + "\n")
+			}
+		}
+
+		// Output stays collapsed unless expanded; the running step shows its latest line
+		var lines []string
+		switch {
+		case m.expanded[step.id]:
+			lines = tail(step.output, m.outputHeight())
+		case step.result == nil:
+			lines = tail(step.output, collapsedLines)
+		}
+		for _, l := range lines {
+			s.WriteString("      " + outputStyle.Render("│ "+l) + "\n")
+		}
+	}
+
+	help := "↑/↓: select step • space/tab: expand output • ctrl+c: abort"
+	if m.phase == phaseDone {
+		if m.runErr != nil {
+			s.WriteString("\n" + errorStyle.Render("❌ "+m.runErr.Error()) + "\n")
+		} else {
+			s.WriteString("\n" + successStyle.Render("✅ Setup complete! Your development environment is ready.") + "\n")
+		}
+		help = "↑/↓: select step • space/tab: expand output • enter/q: exit"
+	}
+	s.WriteString(helpStyle.Render(help))
+
+	return s.String()
+}
+
+// outputHeight is how many output lines an expanded step shows
+func (m Model) outputHeight() int {
+	if m.height <= 0 {
+		return 15
+	}
+	return max(m.height-len(m.steps)-12, 5)
+}
+
+func tail(lines []string, n int) []string {
+	if len(lines) > n {
+		return lines[len(lines)-n:]
+	}
+	return lines
+}
+
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	if d < time.Minute {
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+	return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+}
@@ -0,0 +1,57 @@
+package tui_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/whexy/wenxuan-dev-init/pkg/events"
+	"github.com/whexy/wenxuan-dev-init/pkg/tui"
+	"github.com/whexy/wenxuan-dev-init/pkg/tui/tuitest"
+)
+
+func TestInterruptCancelsTheRun(t *testing.T) {
+	started := make(chan struct{})
+	finished := make(chan error, 1)
+	run := func(ctx context.Context, options map[string]bool, sink events.Sink) error {
+		close(started)
+		// Far more events than the TUI buffers; nothing reads them once the program quit
+		for i := 0; i < 10000; i++ {
+			sink.Handle(events.Event{Kind: events.OutputLine, Line: "apt-get output"})
+		}
+		<-ctx.Done()
+		finished <- ctx.Err()
+		return ctx.Err()
+	}
+
+	d := tuitest.NewDriver(tui.NewModelFor(freshHost, nil).WithRun(run)).Press("enter")
+	if len(d.Cmds()) != 1 {
+		t.Fatalf("confirming returned %d commands, want the batch that starts the run", len(d.Cmds()))
+	}
+	// Start the run the way the program would, but never read its events
+	batch, ok := d.Cmds()[0]().(tea.BatchMsg)
+	if !ok {
+		t.Fatal("confirming did not return a batch of commands")
+	}
+	for _, cmd := range batch {
+		if cmd != nil {
+			go cmd()
+		}
+	}
+	<-started
+
+	d.Press("ctrl+c")
+	if !d.Model().(tui.Model).Interrupted() {
+		t.Error("ctrl+c during the run was not recorded as an interruption")
+	}
+
+	select {
+	case err := <-finished:
+		if err != context.Canceled {
+			t.Errorf("run context error = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the run was not cancelled or blocked publishing events")
+	}
+}
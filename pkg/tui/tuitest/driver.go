@@ -0,0 +1,120 @@
+// Package tuitest drives bubbletea models without a terminal and compares their views with golden files
+package tuitest
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Driver feeds messages to a model the way a bubbletea program would, but synchronously and without running commands
+type Driver struct {
+	model tea.Model
+	cmds  []tea.Cmd
+}
+
+// NewDriver returns a driver for m; m.Init is not called
+func NewDriver(m tea.Model) *Driver {
+	return &Driver{model: m}
+}
+
+// Send passes msg to the model's Update and keeps the model and command it returns
+func (d *Driver) Send(msg tea.Msg) *Driver {
+	var cmd tea.Cmd
+	d.model, cmd = d.model.Update(msg)
+	if cmd != nil {
+		d.cmds = append(d.cmds, cmd)
+	}
+	return d
+}
+
+// Resize sends a window size
+func (d *Driver) Resize(width, height int) *Driver {
+	return d.Send(tea.WindowSizeMsg{Width: width, Height: height})
+}
+
+// Press sends key presses by name: "up", "down", "enter", "space", "tab", "ctrl+c", "esc",
+// or any other string, which is typed as runes
+func (d *Driver) Press(keys ...string) *Driver {
+	for _, k := range keys {
+		d.Send(keyMsg(k))
+	}
+	return d
+}
+
+// namedKeys are the key names Press understands besides plain runes
+var namedKeys = map[string]tea.KeyType{
+	"up":     tea.KeyUp,
+	"down":   tea.KeyDown,
+	"left":   tea.KeyLeft,
+	"right":  tea.KeyRight,
+	"enter":  tea.KeyEnter,
+	"space":  tea.KeySpace,
+	"tab":    tea.KeyTab,
+	"esc":    tea.KeyEsc,
+	"ctrl+c": tea.KeyCtrlC,
+}
+
+func keyMsg(name string) tea.KeyMsg {
+	if t, ok := namedKeys[name]; ok {
+		msg := tea.KeyMsg{Type: t}
+		if t == tea.KeySpace {
+			msg.Runes = []rune{' '}
+		}
+		return msg
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(name)}
+}
+
+// Model returns the current model
+func (d *Driver) Model() tea.Model {
+	return d.model
+}
+
+// View renders the current model
+func (d *Driver) View() string {
+	return d.model.View()
+}
+
+// Cmds returns the commands Update returned so far, without running them
+func (d *Driver) Cmds() []tea.Cmd {
+	return d.cmds
+}
+
+// AssertGolden compares got with the golden file at path and fails t when they differ.
+// With update, it writes got to the file instead.
+func AssertGolden(t testing.TB, path, got string, update bool) {
+	t.Helper()
+	// Trailing spaces are padding and hard to see in diffs, so they are not part of the snapshot
+	got = trimLines(got)
+
+	if update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run the tests with -update to create it)", err)
+	}
+	if !bytes.Equal(want, []byte(got)) {
+		t.Errorf("view differs from %s (run the tests with -update to accept it)\n--- want\n%s\n--- got\n%s", path, want, got)
+	}
+}
+
+func trimLines(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -5,10 +5,39 @@ import (
 	"fmt"
 	"os"
 	"strings"
+
+	"github.com/whexy/wenxuan-dev-init/pkg/events"
 )
 
+// terminalHook, when set, wraps anything that needs direct access to the terminal
+var terminalHook func(fn func() error) error
+
+// SetTerminalHook installs a hook that hands the terminal over while fn runs.
+// A full-screen UI uses it to release the screen for prompts and interactive commands.
+func SetTerminalHook(hook func(fn func() error) error) {
+	terminalHook = hook
+}
+
+// WithTerminal runs fn with direct access to the terminal
+func WithTerminal(fn func() error) error {
+	if terminalHook == nil {
+		return fn()
+	}
+	return terminalHook(fn)
+}
+
 // AskYesNo prompts the user with a yes/no question and returns true for yes
 func AskYesNo(question string) bool {
+	events.Publish(events.Event{Kind: events.PromptRequested, Prompt: question})
+	var answer bool
+	WithTerminal(func() error {
+		answer = askYesNo(question)
+		return nil
+	})
+	return answer
+}
+
+func askYesNo(question string) bool {
 	reader := bufio.NewReader(os.Stdin)
 
 	for {
@@ -34,19 +63,29 @@ func AskYesNo(question string) bool {
 
 // PressEnterToContinue waits for the user to press Enter
 func PressEnterToContinue() {
-	fmt.Print("Press Enter to continue...")
-	bufio.NewReader(os.Stdin).ReadBytes('\n')
-	fmt.Println()
+	events.Publish(events.Event{Kind: events.PromptRequested, Prompt: "Press Enter to continue"})
+	WithTerminal(func() error {
+		fmt.Print("Press Enter to continue...")
+		bufio.NewReader(os.Stdin).ReadBytes('\n')
+		fmt.Println()
+		return nil
+	})
 }
 
 // AskString prompts the user for a string input
 func AskString(question string) string {
-	reader := bufio.NewReader(os.Stdin)
-	fmt.Printf("%s: ", question)
-	response, err := reader.ReadString('\n')
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading input: %v\n", err)
-		return ""
-	}
-	return strings.TrimSpace(response)
+	events.Publish(events.Event{Kind: events.PromptRequested, Prompt: question})
+	var response string
+	WithTerminal(func() error {
+		reader := bufio.NewReader(os.Stdin)
+		fmt.Printf("%s: ", question)
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading input: %v\n", err)
+			return err
+		}
+		response = strings.TrimSpace(line)
+		return nil
+	})
+	return response
 }